@@ -0,0 +1,98 @@
+package wfobj
+
+// Axis aligned bounding box and bounding sphere of a set of vertices
+type Bounds struct {
+	// corners of the box
+	Min, Max Vertex
+	// center of the box and of the sphere
+	Center Vertex
+	// radius of the sphere
+	Radius float32
+	// true if there are no vertices, all the other fields are zero
+	Empty bool
+}
+
+// Size of the box in each axis
+func (b *Bounds) Size() Vertex {
+	return b.Max.Sub(b.Min)
+}
+
+// Return the bounds of the vertices passed by each to visit
+func vertexBounds(each func(visit func(v Vertex))) (b Bounds) {
+	b.Empty = true
+	each(func(v Vertex) {
+		if b.Empty {
+			b.Min, b.Max = v, v
+			b.Empty = false
+			return
+		}
+		b.Min = Vertex{min32(b.Min.X, v.X), min32(b.Min.Y, v.Y), min32(b.Min.Z, v.Z)}
+		b.Max = Vertex{max32(b.Max.X, v.X), max32(b.Max.Y, v.Y), max32(b.Max.Z, v.Z)}
+	})
+	if b.Empty {
+		return
+	}
+
+	b.Center = b.Min.Lerp(b.Max, 0.5)
+	each(func(v Vertex) {
+		b.Radius = max32(b.Radius, v.Distance(b.Center))
+	})
+	return
+}
+
+// Return the bounds of the vertices of the faces in the ranges
+func (m *Mesh) RangeBounds(ranges []FaceRange) Bounds {
+	return vertexBounds(func(visit func(v Vertex)) {
+		for _, r := range ranges {
+			for i := r.Start; i < r.End; i++ {
+				for _, v := range m.Faces[i].Vertices {
+					visit(v)
+				}
+			}
+		}
+	})
+}
+
+// Return the bounds of all the vertices of the mesh, including
+// the points and lines
+func (m *Mesh) Bounds() Bounds {
+	return vertexBounds(func(visit func(v Vertex)) {
+		for i, _ := range m.Faces {
+			for _, v := range m.Faces[i].Vertices {
+				visit(v)
+			}
+		}
+		for _, v := range m.Points {
+			visit(v)
+		}
+		for i, _ := range m.Lines {
+			for _, v := range m.Lines[i].Vertices {
+				visit(v)
+			}
+		}
+	})
+}
+
+// Return the bounds of the faces of the object
+func (o *Object) Bounds(m *Mesh) Bounds {
+	return m.RangeBounds(o.Ranges)
+}
+
+// Return the bounds of the faces of the group
+func (g *Group) Bounds(m *Mesh) Bounds {
+	return m.RangeBounds(g.Ranges)
+}
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
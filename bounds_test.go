@@ -0,0 +1,76 @@
+package wfobj
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMeshBounds(t *testing.T) {
+	m, err := LoadMeshFromFile("cube.obj")
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	b := m.Bounds()
+	if b.Empty {
+		t.Fatalf("Bounds of the cube must not be empty")
+	}
+	if !b.Min.ApproxEqual(Vertex{-1, -1, -1}, 1e-5) || !b.Max.ApproxEqual(Vertex{1, 1, 1}, 1e-5) {
+		t.Errorf("Invalid box %v %v", b.Min, b.Max)
+	}
+	if !b.Center.ApproxEqual(Vertex{}, 1e-5) {
+		t.Errorf("Invalid center %v", b.Center)
+	}
+	if math.Abs(float64(b.Radius)-math.Sqrt(3)) > 1e-5 {
+		t.Errorf("Invalid radius %v", b.Radius)
+	}
+
+	empty := (&Mesh{}).Bounds()
+	if !empty.Empty {
+		t.Errorf("Bounds of an empty mesh must be empty")
+	}
+}
+
+func TestPointCloudBounds(t *testing.T) {
+	m, err := LoadMeshFromReader(strings.NewReader("v 1 1 1\nv 3 3 3\nv 0 5 0\np 1 2\nl 2 3\n"))
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	b := m.Bounds()
+	if b.Empty || !b.Min.Same(Vertex{0, 1, 0}) || !b.Max.Same(Vertex{3, 5, 3}) {
+		t.Fatalf("Invalid bounds of the points and lines %v", b)
+	}
+
+	m.Normalize()
+	b = m.Bounds()
+	if !b.Min.ApproxEqual(Vertex{-0.375, -0.5, -0.375}, 1e-5) || !b.Max.ApproxEqual(Vertex{0.375, 0.5, 0.375}, 1e-5) {
+		t.Errorf("Invalid bounds after normalize %v %v", b.Min, b.Max)
+	}
+}
+
+func TestObjectBounds(t *testing.T) {
+	p := NewLiteralParser(`v 0.0 0.0 0.0
+v 1.0 0.0 0.0
+v 0.0 2.0 0.0
+v 5.0 5.0 5.0
+o Small
+f 1 2 3
+o Far
+g tip
+f 2 3 4
+`)
+	go p.Parse()
+
+	m, err := LoadModel(p.Tokens)
+	if err != nil {
+		t.Fatalf("Unable to load model: %v", err)
+	}
+	b := m.Object("Small").Bounds(m.Mesh)
+	if !b.Min.Same(Vertex{0, 0, 0}) || !b.Max.Same(Vertex{1, 2, 0}) || !b.Center.Same(Vertex{0.5, 1, 0}) {
+		t.Errorf("Invalid bounds for Small %v", b)
+	}
+	b = m.Object("Far").Group("tip").Bounds(m.Mesh)
+	if !b.Min.Same(Vertex{0, 0, 0}) || !b.Max.Same(Vertex{5, 5, 5}) {
+		t.Errorf("Invalid bounds for tip %v", b)
+	}
+}
@@ -18,9 +18,9 @@ var testdata = []TestData{
 		title:  "Simple mesh",
 		ignore: false,
 		mesh: &Mesh{
-			[]Face{
-				Face{VertexList{Vertex{1.0, 1.0, 1.0}, Vertex{0.0, 1.0, 0.0}}, VertexList{}},
-				Face{VertexList{Vertex{0.0, 1.0, 0.0}, Vertex{1.0, 1.0, 1.0}}, VertexList{}},
+			Faces: []Face{
+				Face{Vertices: VertexList{Vertex{1.0, 1.0, 1.0}, Vertex{0.0, 1.0, 0.0}}},
+				Face{Vertices: VertexList{Vertex{0.0, 1.0, 0.0}, Vertex{1.0, 1.0, 1.0}}},
 			},
 		},
 		objlit: `# comment
@@ -43,12 +43,12 @@ f 2 1
 			Token{"1.0", NumberLit, Position{}},
 			Token{"0.0", NumberLit, Position{}},
 
-			// Face	
+			// Face
 			Token{"", FaceDecl, Position{}},
 			Token{"1", NumberLit, Position{}},
 			Token{"2", NumberLit, Position{}},
 
-			// Face	
+			// Face
 			Token{"", FaceDecl, Position{}},
 			Token{"2", NumberLit, Position{}},
 			Token{"1", NumberLit, Position{}},
@@ -62,11 +62,11 @@ f 2 1
 		title:  "Mesh with normals",
 		ignore: false,
 		mesh: &Mesh{
-			[]Face{
-				Face{VertexList{Vertex{1.0, 1.0, 1.0}, Vertex{0.0, 1.0, 0.0}}, VertexList{
+			Faces: []Face{
+				Face{Vertices: VertexList{Vertex{1.0, 1.0, 1.0}, Vertex{0.0, 1.0, 0.0}}, Normals: VertexList{
 					Vertex{1.0, 1.0, 1.0}, Vertex{0.0, 1.0, 0.0},
 				}},
-				Face{VertexList{Vertex{0.0, 1.0, 0.0}, Vertex{1.0, 1.0, 1.0}}, VertexList{
+				Face{Vertices: VertexList{Vertex{0.0, 1.0, 0.0}, Vertex{1.0, 1.0, 1.0}}, Normals: VertexList{
 					Vertex{0.0, 1.0, 0.0}, Vertex{1.0, 1.0, 1.0},
 				}},
 			},
@@ -105,7 +105,7 @@ f 2//2 1//1
 			Token{"1.0", NumberLit, Position{}},
 			Token{"0.0", NumberLit, Position{}},
 
-			// Face	
+			// Face
 			Token{"", FaceDecl, Position{}},
 			Token{"1", NumberLit, Position{}},
 			Token{"", SlashLit, Position{}},
@@ -118,7 +118,7 @@ f 2//2 1//1
 			Token{"", SlashLit, Position{}},
 			Token{"2", NumberLit, Position{}},
 
-			// Face	
+			// Face
 			Token{"", FaceDecl, Position{}},
 			Token{"2", NumberLit, Position{}},
 			Token{"", SlashLit, Position{}},
@@ -134,6 +134,210 @@ f 2//2 1//1
 			Token{"", Eof, Position{}},
 		},
 	},
+
+	// Mesh with texture coordinates
+	{
+		title:  "Mesh with texture coordinates",
+		ignore: false,
+		mesh: &Mesh{
+			Faces: []Face{
+				Face{Vertices: VertexList{Vertex{1.0, 1.0, 1.0}, Vertex{0.0, 1.0, 0.0}}, TexCoords: VertexList{
+					Vertex{0.5, 1.0, 0.0}, Vertex{0.25, 0.0, 0.0},
+				}},
+				Face{Vertices: VertexList{Vertex{0.0, 1.0, 0.0}, Vertex{1.0, 1.0, 1.0}}, TexCoords: VertexList{
+					Vertex{0.25, 0.0, 0.0}, Vertex{0.5, 1.0, 0.0},
+				}, Normals: VertexList{
+					Vertex{0.0, 1.0, 0.0}, Vertex{0.0, 1.0, 0.0},
+				}},
+			},
+		},
+		objlit: `# comment
+v 1.0 1.0 1.0
+v 0.0 1.0 0.0
+vt 0.5 1.0
+vt 0.25
+vn 0.0 1.0 0.0
+f 1/1 2/2
+f 2/2/1 1/1/1
+`,
+		tokens: []Token{
+
+			// Vertex
+			Token{"", VertexDecl, Position{}},
+			Token{"1.0", NumberLit, Position{}},
+			Token{"1.0", NumberLit, Position{}},
+			Token{"1.0", NumberLit, Position{}},
+
+			// Vertex
+			Token{"", VertexDecl, Position{}},
+			Token{"0.0", NumberLit, Position{}},
+			Token{"1.0", NumberLit, Position{}},
+			Token{"0.0", NumberLit, Position{}},
+
+			// Texture coordinates
+			Token{"", TexCoordDecl, Position{}},
+			Token{"0.5", NumberLit, Position{}},
+			Token{"1.0", NumberLit, Position{}},
+
+			Token{"", TexCoordDecl, Position{}},
+			Token{"0.25", NumberLit, Position{}},
+
+			// Normal
+			Token{"", NormalDecl, Position{}},
+			Token{"0.0", NumberLit, Position{}},
+			Token{"1.0", NumberLit, Position{}},
+			Token{"0.0", NumberLit, Position{}},
+
+			// Face
+			Token{"", FaceDecl, Position{}},
+			Token{"1", NumberLit, Position{}},
+			Token{"", SlashLit, Position{}},
+			Token{"1", NumberLit, Position{}},
+
+			// vector
+			Token{"2", NumberLit, Position{}},
+			Token{"", SlashLit, Position{}},
+			Token{"2", NumberLit, Position{}},
+
+			// Face
+			Token{"", FaceDecl, Position{}},
+			Token{"2", NumberLit, Position{}},
+			Token{"", SlashLit, Position{}},
+			Token{"2", NumberLit, Position{}},
+			Token{"", SlashLit, Position{}},
+			Token{"1", NumberLit, Position{}},
+
+			// vector
+			Token{"1", NumberLit, Position{}},
+			Token{"", SlashLit, Position{}},
+			Token{"1", NumberLit, Position{}},
+			Token{"", SlashLit, Position{}},
+			Token{"1", NumberLit, Position{}},
+
+			Token{"", Eof, Position{}},
+		},
+	},
+
+	// Mesh with materials
+	{
+		title:  "Mesh with materials",
+		ignore: false,
+		mesh: &Mesh{
+			Faces: []Face{
+				Face{Vertices: VertexList{Vertex{1.0, 1.0, 1.0}, Vertex{0.0, 1.0, 0.0}}},
+				Face{Vertices: VertexList{Vertex{0.0, 1.0, 0.0}, Vertex{1.0, 1.0, 1.0}}, Material: &Material{Name: "Red"}},
+				Face{Vertices: VertexList{Vertex{1.0, 1.0, 1.0}, Vertex{0.0, 1.0, 0.0}}, Material: &Material{Name: "Dark Blue"}},
+			},
+		},
+		objlit: `# comment
+mtllib colors.mtl
+v 1.0 1.0 1.0
+v 0.0 1.0 0.0
+f 1 2
+usemtl Red
+f 2 1
+usemtl Dark Blue
+f 1 2
+`,
+		tokens: []Token{
+
+			// Material library
+			Token{"", MaterialLibDecl, Position{}},
+			Token{"colors.mtl", NameLit, Position{}},
+
+			// Vertex
+			Token{"", VertexDecl, Position{}},
+			Token{"1.0", NumberLit, Position{}},
+			Token{"1.0", NumberLit, Position{}},
+			Token{"1.0", NumberLit, Position{}},
+
+			// Vertex
+			Token{"", VertexDecl, Position{}},
+			Token{"0.0", NumberLit, Position{}},
+			Token{"1.0", NumberLit, Position{}},
+			Token{"0.0", NumberLit, Position{}},
+
+			// Face
+			Token{"", FaceDecl, Position{}},
+			Token{"1", NumberLit, Position{}},
+			Token{"2", NumberLit, Position{}},
+
+			// Material
+			Token{"", UseMaterialDecl, Position{}},
+			Token{"Red", NameLit, Position{}},
+
+			// Face
+			Token{"", FaceDecl, Position{}},
+			Token{"2", NumberLit, Position{}},
+			Token{"1", NumberLit, Position{}},
+
+			// Material
+			Token{"", UseMaterialDecl, Position{}},
+			Token{"Dark", NameLit, Position{}},
+			Token{"Blue", NameLit, Position{}},
+
+			// Face
+			Token{"", FaceDecl, Position{}},
+			Token{"1", NumberLit, Position{}},
+			Token{"2", NumberLit, Position{}},
+
+			Token{"", Eof, Position{}},
+		},
+	},
+
+	// Mesh with smoothing groups
+	{
+		title:  "Mesh with smoothing groups",
+		ignore: false,
+		mesh: &Mesh{
+			Faces: []Face{
+				Face{Vertices: VertexList{Vertex{1.0, 1.0, 1.0}, Vertex{0.0, 1.0, 0.0}}},
+				Face{Vertices: VertexList{Vertex{0.0, 1.0, 0.0}, Vertex{1.0, 1.0, 1.0}}, SmoothingGroup: 2},
+			},
+		},
+		objlit: `# comment
+v 1.0 1.0 1.0
+v 0.0 1.0 0.0
+s off
+f 1 2
+s 2
+f 2 1
+`,
+		tokens: []Token{
+
+			// Vertex
+			Token{"", VertexDecl, Position{}},
+			Token{"1.0", NumberLit, Position{}},
+			Token{"1.0", NumberLit, Position{}},
+			Token{"1.0", NumberLit, Position{}},
+
+			// Vertex
+			Token{"", VertexDecl, Position{}},
+			Token{"0.0", NumberLit, Position{}},
+			Token{"1.0", NumberLit, Position{}},
+			Token{"0.0", NumberLit, Position{}},
+
+			// Smoothing group
+			Token{"", SmoothingDecl, Position{}},
+			Token{"off", NameLit, Position{}},
+
+			// Face
+			Token{"", FaceDecl, Position{}},
+			Token{"1", NumberLit, Position{}},
+			Token{"2", NumberLit, Position{}},
+
+			// Smoothing group
+			Token{"", SmoothingDecl, Position{}},
+			Token{"2", NumberLit, Position{}},
+
+			// Face
+			Token{"", FaceDecl, Position{}},
+			Token{"2", NumberLit, Position{}},
+			Token{"1", NumberLit, Position{}},
+
+			Token{"", Eof, Position{}},
+		},
+	},
 }
 
 type PrintState struct{}
@@ -0,0 +1,144 @@
+package wfobj
+
+import (
+	"fmt"
+)
+
+// Basis of a free-form curve or surface, set by cstype
+type CurveType int
+
+const (
+	BasisMatrix = CurveType(iota)
+	Bezier
+	BSpline
+	Cardinal
+	Taylor
+)
+
+var curveTypeNames = map[string]CurveType{
+	"bmatrix":  BasisMatrix,
+	"bezier":   Bezier,
+	"bspline":  BSpline,
+	"cardinal": Cardinal,
+	"taylor":   Taylor,
+}
+
+func (c CurveType) String() string {
+	for name, t := range curveTypeNames {
+		if t == c {
+			return name
+		}
+	}
+	return fmt.Sprintf("CurveType(%v)", int(c))
+}
+
+// Approximation technique of a ctech or stech statement
+type TechniqueKind int
+
+const (
+	// No ctech or stech statement, DefaultResolution is used
+	NoTechnique = TechniqueKind(iota)
+	// cparm res or cparma ures vres
+	ParamTechnique
+	// cparmb uvres
+	ParamBTechnique
+	// cspace maxlength
+	SpaceTechnique
+	// curv maxdist maxangle
+	CurvatureTechnique
+)
+
+// Resolution used by elements without an approximation technique
+const DefaultResolution = 4
+
+// Approximation technique used to tessellate curves and surfaces
+type Technique struct {
+	Kind TechniqueKind
+	// cparm, cparma and cparmb, ResolutionV is only used by surfaces
+	ResolutionU, ResolutionV float32
+	// cspace
+	MaxLength float32
+	// curv, MaxAngle is in degrees
+	MaxDistance, MaxAngle float32
+}
+
+// Attributes shared by free-form curves and surfaces
+//
+// Type, Rational, degrees, basis matrices, steps and technique are
+// copied from the last cstype, deg, bmat, step and ctech (for curves)
+// or stech (for surfaces) statements, the parameters are set by the
+// parm statements of the element
+type FreeForm struct {
+	Type CurveType
+	// true for cstype rat, the w of the control points are their weights
+	Rational bool
+	// deg, DegreeV is zero for curves
+	DegreeU, DegreeV int
+	// bmat u and bmat v, stored by rows
+	BasisU, BasisV []float32
+	// step
+	StepU, StepV int
+	// parm u and parm v
+	ParamsU, ParamsV []float32
+	// ctech or stech, not used by Curve2D
+	Technique Technique
+}
+
+// A curve in 3D space, declared by curv
+type Curve struct {
+	FreeForm
+	// global parameters of the start and end of the curve
+	Start, End float32
+	// positions of the v statements referenced by the curve
+	ControlPoints VertexList
+	// w of each control point
+	Weights []float32
+}
+
+// A curve in the parameter space of a surface, declared by curv2
+//
+// Used by trimming loops and special curves
+type Curve2D struct {
+	FreeForm
+	// u v w of the vp statements referenced by the curve, w is one if absent
+	ControlPoints VertexList
+}
+
+// A segment of a Curve2D used by a trimming loop or special curve
+type CurveRef struct {
+	// parameters of the start and end of the segment
+	Start, End float32
+	// index of the curve in Curves2D
+	Curve int
+}
+
+// Curve segments that make a closed loop or a special curve
+type Loop []CurveRef
+
+// A surface, declared by surf
+type Surface struct {
+	FreeForm
+	// parameter ranges of the surface
+	StartU, EndU, StartV, EndV float32
+	// positions of the v statements referenced by the surface,
+	// u varies faster than v
+	ControlPoints VertexList
+	// w of each control point
+	Weights []float32
+	// either empty or one entry for each control point
+	TexCoords VertexList
+	Normals   VertexList
+	// outer boundaries (trim), holes (hole) and special curves (scrv)
+	Trims, Holes, SpecialCurves []Loop
+	// material active when the surface was declared, nil if none
+	Material *Material
+}
+
+// Free-form geometry of a mesh
+type FreeFormGeometry struct {
+	// u v w of the vp statements, w is one if absent
+	ParamVertices VertexList
+	Curves        []Curve
+	Curves2D      []Curve2D
+	Surfaces      []Surface
+}
@@ -0,0 +1,148 @@
+package wfobj
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// A bicubic bezier patch trimmed by a square with a triangular hole
+const freeformlit = `v 0.0 0.0 0.0
+v 1.0 0.0 0.0
+v 2.0 0.0 0.0
+v 3.0 0.0 0.0 2.0
+v 0.0 1.0 0.0
+v 1.0 1.0 1.0
+v 2.0 1.0 1.0
+v 3.0 1.0 0.0
+v 0.0 2.0 0.0
+v 1.0 2.0 1.0
+v 2.0 2.0 1.0
+v 3.0 2.0 0.0
+v 0.0 3.0 0.0
+v 1.0 3.0 0.0
+v 2.0 3.0 0.0
+v 3.0 3.0 0.0
+vp 0.1 0.1
+vp 0.9 0.1
+vp 0.9 0.9
+vp 0.1 0.9
+vp 0.1 0.1
+vp 0.4 0.4
+vp 0.6 0.4
+vp 0.5 0.6
+vp 0.4 0.4
+cstype bezier
+deg 1
+curv2 1 2 3 4 5
+parm u 0.0 1.0 2.0 3.0 4.0
+end
+curv2 6 7 8 9
+parm u 0.0 1.0 2.0 3.0
+end
+cstype rat bezier
+deg 3
+curv 0.0 1.0 1 2 3 4
+parm u 0.0 1.0
+end
+cstype bezier
+deg 3 3
+surf 0.0 1.0 0.0 1.0 1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16
+parm u 0.0 1.0
+parm v 0.0 1.0
+trim 0.0 4.0 1
+hole 0.0 3.0 -1
+end
+`
+
+func TestFreeFormParser(t *testing.T) {
+	p := NewLiteralParser("vp 0.5 0.5\ncstype rat bspline\nbmat u 1 0 0 1\nparm v 0.0 1.0\nsurf 0 1 0 1 1/1/1 2//2\nend\n")
+	expected := []Kind{
+		ParamVertexDecl, NumberLit, NumberLit,
+		CurveTypeDecl, NameLit, NameLit,
+		BasisMatrixDecl, NameLit, NumberLit, NumberLit, NumberLit, NumberLit,
+		ParamDecl, NameLit, NumberLit, NumberLit,
+		SurfaceDecl, NumberLit, NumberLit, NumberLit, NumberLit,
+		NumberLit, SlashLit, NumberLit, SlashLit, NumberLit, NumberLit, SlashLit, SlashLit, NumberLit,
+		EndDecl, Eof,
+	}
+	for i, k := range expected {
+		tok, err := p.NextToken()
+		if err != nil {
+			t.Fatalf("Unable to read token %v. %v", i, err)
+		}
+		if tok.Kind != k {
+			t.Fatalf("Expecting %v at %v but got %v", k, i, &tok)
+		}
+	}
+}
+
+func TestFreeFormLoader(t *testing.T) {
+	m, err := LoadIndexedMeshFromReader(strings.NewReader(freeformlit))
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	ff := &m.FreeForm
+	if len(ff.ParamVertices) != 9 || len(ff.Curves2D) != 2 || len(ff.Curves) != 1 || len(ff.Surfaces) != 1 {
+		t.Fatalf("Expecting 9 parameter vertices, 2 curves2d, 1 curve and 1 surface got %v %v %v %v",
+			len(ff.ParamVertices), len(ff.Curves2D), len(ff.Curves), len(ff.Surfaces))
+	}
+	if !ff.ParamVertices[0].Same(Vertex{0.1, 0.1, 1}) {
+		t.Errorf("Invalid parameter vertex %v", ff.ParamVertices[0])
+	}
+
+	c2 := &ff.Curves2D[1]
+	if c2.Type != Bezier || c2.DegreeU != 1 || len(c2.ControlPoints) != 4 || len(c2.ParamsU) != 4 {
+		t.Errorf("Invalid curve2d %v", c2)
+	}
+
+	c := &ff.Curves[0]
+	if !c.Rational || c.DegreeU != 3 || c.End != 1 || len(c.ControlPoints) != 4 {
+		t.Errorf("Invalid curve %v", c)
+	}
+	if c.Weights[3] != 2 || c.Weights[0] != 1 {
+		t.Errorf("Invalid curve weights %v", c.Weights)
+	}
+
+	s := &ff.Surfaces[0]
+	if s.Rational || s.DegreeU != 3 || s.DegreeV != 3 || len(s.ControlPoints) != 16 {
+		t.Errorf("Invalid surface %v", s)
+	}
+	if len(s.ParamsU) != 2 || len(s.ParamsV) != 2 {
+		t.Errorf("Invalid surface parameters %v %v", s.ParamsU, s.ParamsV)
+	}
+	if len(s.Trims) != 1 || len(s.Holes) != 1 || s.Trims[0][0] != (CurveRef{0, 4, 0}) || s.Holes[0][0] != (CurveRef{0, 3, 1}) {
+		t.Errorf("Invalid trimming loops %v %v", s.Trims, s.Holes)
+	}
+}
+
+func TestFreeFormLoaderErrors(t *testing.T) {
+	for _, lit := range []string{
+		"vp 0 0\nvp 1 1\ncurv2 1 2\n",
+		"parm u 0 1\n",
+		"vp 0 0\nvp 1 1\ncurv2 1 2\ntrim 0 1 1\nend\n",
+		"cstype nurbs\n",
+		"end\n",
+		"deg\n",
+		"deg 1 2 3\n",
+	} {
+		if _, err := LoadIndexedMeshFromReader(strings.NewReader(lit)); err == nil {
+			t.Errorf("Loading %q must fail", lit)
+		}
+	}
+
+	for _, test := range []struct {
+		lit string
+		pos Position
+	}{
+		{"deg 2.7\n", Position{1, 5}},
+		{"step 1 1.5\n", Position{1, 8}},
+		{"deg 99999999999999999999\n", Position{1, 5}},
+	} {
+		_, err := LoadIndexedMeshFromReader(strings.NewReader(test.lit))
+		var lerr *MeshLoadError
+		if !errors.As(err, &lerr) || lerr.Pos != test.pos {
+			t.Errorf("Expecting a MeshLoadError at %v for %q but got %v", &test.pos, test.lit, err)
+		}
+	}
+}
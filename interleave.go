@@ -0,0 +1,135 @@
+package wfobj
+
+// An attribute of the vertices of an interleaved buffer
+type Attribute int
+
+const (
+	// x y z of the vertex
+	PositionAttr Attribute = iota
+	// x y z of the normal
+	NormalAttr
+	// u v of the texture coordinate
+	TexCoordAttr
+	// r g b of the vertex color
+	ColorAttr
+)
+
+// Return the number of floats used by the attribute
+func (a Attribute) Size() int {
+	switch a {
+	case TexCoordAttr:
+		return 2
+	}
+	return 3
+}
+
+// The attributes of each vertex of an interleaved buffer, in order
+type Layout []Attribute
+
+// Return the number of floats used by each vertex
+func (l Layout) Stride() (stride int) {
+	for _, a := range l {
+		stride += a.Size()
+	}
+	return
+}
+
+// Return the position of the attribute inside a vertex in floats,
+// or -1 if the layout doesn't have it
+func (l Layout) Offset(attr Attribute) int {
+	offset := 0
+	for _, a := range l {
+		if a == attr {
+			return offset
+		}
+		offset += a.Size()
+	}
+	return -1
+}
+
+// The attributes of a vertex used to find duplicates
+type interleaveKey struct {
+	position, normal, texcoord Vertex
+	color                      Color
+}
+
+type interleaver struct {
+	layout   Layout
+	stride   int
+	vertices []float32
+	indices  []uint32
+	unique   map[interleaveKey]uint32
+}
+
+// Return the attributes of the corner of the face used by the layout
+//
+// Normals and texture coordinates are zero and colors are white
+// if the face doesn't have one for each vertex
+func (il *interleaver) key(f *Face, corner int) (k interleaveKey) {
+	if il.layout.Offset(PositionAttr) >= 0 {
+		k.position = f.Vertices[corner]
+	}
+	if il.layout.Offset(NormalAttr) >= 0 && len(f.Normals) == len(f.Vertices) {
+		k.normal = f.Normals[corner]
+	}
+	if il.layout.Offset(TexCoordAttr) >= 0 && len(f.TexCoords) == len(f.Vertices) {
+		k.texcoord = f.TexCoords[corner]
+	}
+	if il.layout.Offset(ColorAttr) >= 0 {
+		k.color = Color{1, 1, 1}
+		if len(f.Colors) == len(f.Vertices) {
+			k.color = f.Colors[corner]
+		}
+	}
+	return
+}
+
+// Append the index of the vertex, adding it to the buffer if needed
+func (il *interleaver) add(k interleaveKey) {
+	idx, ok := il.unique[k]
+	if !ok {
+		idx = uint32(len(il.vertices) / il.stride)
+		for _, a := range il.layout {
+			switch a {
+			case PositionAttr:
+				il.vertices = append(il.vertices, k.position.X, k.position.Y, k.position.Z)
+			case NormalAttr:
+				il.vertices = append(il.vertices, k.normal.X, k.normal.Y, k.normal.Z)
+			case TexCoordAttr:
+				il.vertices = append(il.vertices, k.texcoord.X, k.texcoord.Y)
+			case ColorAttr:
+				il.vertices = append(il.vertices, k.color.R, k.color.G, k.color.B)
+			}
+		}
+		il.unique[k] = idx
+	}
+	il.indices = append(il.indices, idx)
+}
+
+// Return the vertices of the mesh as a flat buffer with the attributes of
+// the layout, and the indices of the triangles in that buffer
+//
+// Vertices with the same attributes are stored only once. Attributes
+// that aren't in the layout aren't used to find duplicates, missing
+// normals or texture coordinates are stored as zeros and missing
+// colors as white.
+// Faces are triangulated as in Mesh.Triangulate, faces with less
+// than three vertices are ignored
+func (m *Mesh) Interleave(layout Layout) (vertices []float32, indices []uint32) {
+	il := &interleaver{layout, layout.Stride(), make([]float32, 0), make([]uint32, 0), make(map[interleaveKey]uint32)}
+	if il.stride == 0 {
+		return il.vertices, il.indices
+	}
+	for i, _ := range m.Faces {
+		f := &m.Faces[i]
+		if len(f.Vertices) < 3 {
+			continue
+		}
+		for _, tri := range triangulateFace(f) {
+			for _, corner := range tri {
+				il.add(il.key(f, corner))
+			}
+		}
+	}
+	return il.vertices, il.indices
+}
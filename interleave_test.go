@@ -0,0 +1,41 @@
+package wfobj
+
+import (
+	"testing"
+)
+
+func TestInterleave(t *testing.T) {
+	m := &Mesh{Faces: []Face{{
+		Vertices:  VertexList{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}},
+		Normals:   VertexList{{0, 0, 1}, {0, 0, 1}, {0, 0, 1}, {0, 0, 1}},
+		TexCoords: VertexList{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}},
+	}, {
+		// shares an edge with the quad but has no normals
+		Vertices: VertexList{{1, 0, 0}, {2, 0, 0}, {1, 1, 0}},
+	}}}
+
+	layout := Layout{PositionAttr, TexCoordAttr, NormalAttr}
+	if layout.Stride() != 8 || layout.Offset(NormalAttr) != 5 {
+		t.Errorf("Invalid layout stride %v or offset %v", layout.Stride(), layout.Offset(NormalAttr))
+	}
+	vertices, indices := m.Interleave(layout)
+	if len(indices) != 9 {
+		t.Fatalf("Expecting 3 triangles but got %v indices", len(indices))
+	}
+	if len(vertices) != 7*8 {
+		t.Fatalf("Expecting 7 unique vertices but got %v floats", len(vertices))
+	}
+	v := vertices[indices[4]*8 : indices[4]*8+8]
+	expected := []float32{1, 1, 0, 1, 1, 0, 0, 1}
+	for i, _ := range expected {
+		if v[i] != expected[i] {
+			t.Fatalf("Expecting %v but got %v", expected, v)
+		}
+	}
+
+	// without normals and texture coordinates the shared corners are merged
+	vertices, indices = m.Interleave(Layout{PositionAttr})
+	if len(vertices) != 5*3 || len(indices) != 9 {
+		t.Errorf("Expecting 5 unique positions but got %v floats", len(vertices))
+	}
+}
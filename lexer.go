@@ -1,9 +1,14 @@
 package wfobj
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"math"
 	"os"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 )
@@ -13,8 +18,33 @@ type Kind int
 const (
 	VertexDecl = Kind(iota)
 	NormalDecl
+	TexCoordDecl
 	FaceDecl
+	MaterialLibDecl
+	UseMaterialDecl
+	ObjectDecl
+	GroupDecl
+	SmoothingDecl
+	PointDecl
+	LineDecl
+	ParamVertexDecl
+	CurveTypeDecl
+	DegreeDecl
+	BasisMatrixDecl
+	StepDecl
+	CurveDecl
+	Curve2DDecl
+	SurfaceDecl
+	ParamDecl
+	TrimDecl
+	HoleDecl
+	SpecialCurveDecl
+	EndDecl
+	CurveTechDecl
+	SurfaceTechDecl
+	UnknownDecl
 	NumberLit
+	NameLit
 	SlashLit
 	Eof
 	AnyKind
@@ -24,17 +54,57 @@ const (
 	Number       = "0123456789"
 	Minus        = "-"
 	Dot          = "."
+	Plus         = "+"
 	SignedNumber = Minus + Number
 	FloatNumber  = SignedNumber + Dot
+	// runes that can start a number literal, including nan and inf
+	NumberStart = FloatNumber + Plus + "nNiI"
+	// runes that end a number literal
+	NumberEnd = " \n#/"
+)
+
+// Policy for NaN and Inf number literals
+type NonFinitePolicy int
+
+const (
+	// Panic with a parse error when NaN or Inf is found
+	RejectNonFinite = NonFinitePolicy(iota)
+	// Emit NaN and Inf as any other number
+	AllowNonFinite
 )
 
 var kindNames = map[Kind]string{
-	VertexDecl: "VECTOR_DECLARATION",
-	NormalDecl: "NORMAL_DECLARATION",
-	FaceDecl:   "FACE_DECLARATION",
-	NumberLit:  "NUMBER_LITERAL",
-	SlashLit:   "SLASH_LITERAL",
-	Eof:        "EOF",
+	VertexDecl:       "VECTOR_DECLARATION",
+	NormalDecl:       "NORMAL_DECLARATION",
+	TexCoordDecl:     "TEXCOORD_DECLARATION",
+	FaceDecl:         "FACE_DECLARATION",
+	MaterialLibDecl:  "MATERIAL_LIBRARY_DECLARATION",
+	UseMaterialDecl:  "USE_MATERIAL_DECLARATION",
+	ObjectDecl:       "OBJECT_DECLARATION",
+	GroupDecl:        "GROUP_DECLARATION",
+	SmoothingDecl:    "SMOOTHING_DECLARATION",
+	PointDecl:        "POINT_DECLARATION",
+	LineDecl:         "LINE_DECLARATION",
+	ParamVertexDecl:  "PARAM_VERTEX_DECLARATION",
+	CurveTypeDecl:    "CURVE_TYPE_DECLARATION",
+	DegreeDecl:       "DEGREE_DECLARATION",
+	BasisMatrixDecl:  "BASIS_MATRIX_DECLARATION",
+	StepDecl:         "STEP_DECLARATION",
+	CurveDecl:        "CURVE_DECLARATION",
+	Curve2DDecl:      "CURVE2D_DECLARATION",
+	SurfaceDecl:      "SURFACE_DECLARATION",
+	ParamDecl:        "PARAM_DECLARATION",
+	TrimDecl:         "TRIM_DECLARATION",
+	HoleDecl:         "HOLE_DECLARATION",
+	SpecialCurveDecl: "SPECIAL_CURVE_DECLARATION",
+	EndDecl:          "END_DECLARATION",
+	CurveTechDecl:    "CURVE_TECHNIQUE_DECLARATION",
+	SurfaceTechDecl:  "SURFACE_TECHNIQUE_DECLARATION",
+	UnknownDecl:      "UNKNOWN_DECLARATION",
+	NumberLit:        "NUMBER_LITERAL",
+	NameLit:          "NAME_LITERAL",
+	SlashLit:         "SLASH_LITERAL",
+	Eof:              "EOF",
 }
 
 func (k Kind) String() string {
@@ -68,101 +138,238 @@ type Debug interface {
 }
 
 type Parser struct {
-	Contents string
-	VList    VertexList
-	Tokens   chan *Token
-	Debug    Debug
-	sz       int
-	C        rune
-	// position in the stream
-	pos int
-	// current line position
+	VList  VertexList
+	Tokens chan *Token
+	Debug  Debug
+	// What to do with NaN and Inf literals, rejected by default
+	NonFinite NonFinitePolicy
+	// name of the file being parsed, used in errors
+	File   string
+	sz     int
+	C      rune
+	reader *bufio.Reader
+	// byte read when C is an invalid utf-8 code
+	raw byte
+	// closed when the parse ends
+	closer io.Closer
+	// true if the last rune was pushed back
+	back bool
+	// tokens emitted, the ones before head were returned by NextToken
+	pending []Token
+	head    int
+	// true after the Eof token is emitted
+	done bool
+	// error that stopped the parser
+	err error
+	// position of the next rune
 	cPos Position
-	// old line position
+	// position of the last rune read
 	oPos Position
+	// position of the token being read
+	start Position
 }
 
+// Causes of the ParseError returned by the parser
+var (
+	ErrInvalidNumber  = errors.New("invalid number literal")
+	ErrNonFinite      = errors.New("non finite number literal")
+	ErrUnexpectedChar = errors.New("unexpected character")
+)
+
 // An error that happened during the parse of the file
-type ParseError string
+type ParseError struct {
+	// file name, empty if not parsing a file
+	File string
+	// position of the token being read
+	Pos Position
+	// text of the offending token, empty if unknown
+	Val string
+	Msg string
+	// underlying error, if any
+	Err error
+}
 
-// Return the messsage with the current position of the parser
-func NewParseError(p *Parser, msg string) ParseError {
-	return ParseError(fmt.Sprintf("%v %v", msg, p.cPos))
+// Return the error with the position of the token being read
+//
+// If val is an error it is used as the cause
+func NewParseError(p *Parser, val interface{}) *ParseError {
+	err := &ParseError{File: p.File, Pos: p.start, Msg: fmt.Sprintf("%v", val)}
+	if cause, ok := val.(error); ok {
+		err.Err = cause
+	}
+	return err
+}
+
+// Stop the parser with a ParseError caused by err for
+// the offending text val of the token being read
+func (p *Parser) fail(val string, err error) {
+	panic(&ParseError{File: p.File, Pos: p.start, Val: val, Msg: err.Error(), Err: err})
 }
 
 // Error interface
-func (p ParseError) Error() string {
-	return string(p)
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%v: %v", positionPrefix(e.File, e.Pos), e.Msg)
 }
 
-func NewParserFromFile(fileName string) (p *Parser, err error) {
-	file, err := os.Open(fileName)
-	if err != nil {
-		return
+// Return the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Return the file:line:col prefix used in errors
+func positionPrefix(file string, pos Position) string {
+	if len(file) == 0 {
+		return fmt.Sprintf("%v:%v", pos.Line, pos.Col)
 	}
-	defer file.Close()
+	return fmt.Sprintf("%v:%v:%v", file, pos.Line, pos.Col)
+}
 
-	buff, err := ioutil.ReadAll(file)
+// Parse the contents of the file
+//
+// The file is closed when the parser reaches the end of the stream
+// or an error, or by calling Close
+func NewParserFromFile(fileName string) (p *Parser, err error) {
+	file, err := os.Open(fileName)
 	if err != nil {
 		return
 	}
-	p = NewLiteralParser(string(buff))
+	p = NewParser(file)
+	p.closer = file
+	p.File = fileName
 	return
 }
 
 // Parse the contents of the string variable
 func NewLiteralParser(literal string) (p *Parser) {
-	literal = strings.Replace(literal, "\r\n", "\n", -1)
-	p = &Parser{literal, make(VertexList, 0), make(chan *Token, 0), nil, 0, 0, 0, Position{1, 1}, Position{1, 0}}
+	return NewParser(strings.NewReader(literal))
+}
+
+// Parse the contents read from r
+//
+// The contents are read as needed, so only a small buffer is kept in memory.
+// A leading utf-8 byte order mark is skipped
+func NewParser(r io.Reader) (p *Parser) {
+	p = &Parser{
+		VList:     make(VertexList, 0),
+		Tokens:    make(chan *Token, 0),
+		NonFinite: RejectNonFinite,
+		reader:    bufio.NewReader(r),
+		pending:   make([]Token, 0, 8),
+		cPos:      Position{1, 1},
+		oPos:      Position{1, 1},
+		start:     Position{1, 1},
+	}
+	// skip the byte order mark of utf-8 files
+	if bom, _ := p.reader.Peek(3); string(bom) == "\ufeff" {
+		p.reader.Discard(len(bom))
+	}
 	return
 }
 
 // Start the parser and emit the tokens in the Tokens channel
+//
+// This is a wrapper around NextToken, the channel is closed when
+// the Eof token is sent or an error is found
 func (p *Parser) Parse() (err error) {
+	defer close(p.Tokens)
+
+	for {
+		var t Token
+		t, err = p.NextToken()
+		if err != nil {
+			return
+		}
+		p.Tokens <- &t
+		if t.Kind == Eof {
+			return
+		}
+	}
+}
+
+// Return the next token from the stream
+//
+// After the Eof token is returned, all calls return Eof again.
+// If the contents are invalid, the ParseError is returned by
+// this and all the following calls
+func (p *Parser) NextToken() (t Token, err error) {
+	for p.head == len(p.pending) {
+		if p.err != nil {
+			err = p.err
+			return
+		}
+		if p.done {
+			t = Token{"", Eof, p.start}
+			return
+		}
+		p.err = p.lex()
+	}
+	t = p.pending[p.head]
+	p.head++
+	if p.head == len(p.pending) {
+		// reuse the buffer
+		p.pending = p.pending[:0]
+		p.head = 0
+	}
+	return
+}
+
+// Read the stream until at least one token is emitted
+func (p *Parser) lex() (err error) {
 	defer func() {
-		close(p.Tokens)
 		if val := recover(); val != nil {
-			err = NewParseError(p, fmt.Sprintf("%v", val))
+			if perr, ok := val.(*ParseError); ok {
+				err = perr
+			} else {
+				err = NewParseError(p, val)
+			}
+			p.Close()
 		}
 	}()
 
-	for p.Next() {
+	for len(p.pending) == 0 {
+		p.Mark()
+		if !p.Next() {
+			p.Emit("", Eof)
+			p.done = true
+			p.Close()
+			return
+		}
 		switch p.C {
-		case 'v':
-			ok := p.NextIf(" n")
-			if !ok {
-				panic("Expecting Vertex Decl or Normal Decl")
-			}
-			switch p.C {
-			case ' ':
-				p.Emit("", VertexDecl)
-			case 'n':
-				p.Emit("", NormalDecl)
-			}
-			p.ReadNumberList()
-		case 'f':
-			p.Emit("", FaceDecl)
-			p.ReadFaceParts()
+		case ' ', '\n':
+			// blank space between statements
 		case '#':
-			// comment
-			p.DiscardUntil("\n")
-
-		case utf8.RuneError:
-			panic(fmt.Sprintf("Invalid utf-8 code @ %v", p.pos))
+			p.DiscardComment()
+		default:
+			p.PushBack()
+			p.ReadStatement()
 		}
 	}
-	p.Emit("", Eof)
+	return
+}
 
+// Close the file used by the parser, if any
+//
+// Called when the parser reaches the end of the stream or an error
+func (p *Parser) Close() (err error) {
+	if p.closer != nil {
+		err = p.closer.Close()
+		p.closer = nil
+	}
 	return
 }
 
-// Emit a token
+// Mark the position of the next rune as the start of a token
+func (p *Parser) Mark() {
+	p.start = p.cPos
+}
+
+// Emit a token starting at the last marked position
 func (p *Parser) Emit(val string, kind Kind) {
-	t := Token{val, kind, p.cPos}
+	t := Token{val, kind, p.start}
 	if p.Debug != nil {
 		p.Debug.Emit(&t)
 	}
-	p.Tokens <- &t
+	p.pending = append(p.pending, t)
 }
 
 // Discard all chars from the stream that match at least one of the chars passed
@@ -181,19 +388,203 @@ func (p *Parser) DiscardUntil(chars string) {
 	}
 }
 
+// Discard the rest of a comment line
+//
+// The bytes aren't decoded, so comments in other encodings are accepted,
+// and a \ at the end doesn't continue the comment in the next line
+func (p *Parser) DiscardComment() {
+	for {
+		buf, err := p.reader.Peek(1)
+		if len(buf) == 0 {
+			if err != io.EOF {
+				panic(err)
+			}
+			return
+		}
+		buf, _ = p.reader.Peek(p.reader.Buffered())
+		n := bytes.IndexAny(buf, "\r\n")
+		if n == -1 {
+			n = len(buf)
+		}
+		p.reader.Discard(n)
+		p.cPos.Col += n
+		if n < len(buf) {
+			return
+		}
+	}
+}
+
 // Accumulate the runes from the stream while it matches the chars
 func (p *Parser) Acc(chars string) string {
 	acc := ""
 	for p.NextIf(chars) {
-		acc += string(p.C)
+		acc += p.text()
 	}
 	return acc
 }
 
+// Accumulate the runes from the stream until one of the chars is found
+func (p *Parser) AccUntil(chars string) string {
+	acc := ""
+	for {
+		ok, r := p.Peek("")
+		if !ok || strings.ContainsRune(chars, r) {
+			return acc
+		}
+		p.Next()
+		acc += p.text()
+	}
+}
+
+// Read a statement keyword and its arguments
+//
+// Unknown statements are emitted as a UnknownDecl token with the
+// keyword as value and their arguments are discarded
+func (p *Parser) ReadStatement() {
+	p.Mark()
+	keyword := p.AccUntil(" \n#")
+	switch keyword {
+	case "v":
+		p.Emit("", VertexDecl)
+		p.ReadNumberList()
+	case "vn":
+		p.Emit("", NormalDecl)
+		p.ReadNumberList()
+	case "vt":
+		p.Emit("", TexCoordDecl)
+		p.ReadNumberList()
+	case "f":
+		p.Emit("", FaceDecl)
+		p.ReadFaceParts()
+	case "mtllib":
+		p.Emit("", MaterialLibDecl)
+		p.ReadNameList()
+	case "usemtl":
+		p.Emit("", UseMaterialDecl)
+		p.ReadNameList()
+	case "o":
+		p.Emit("", ObjectDecl)
+		p.ReadNameList()
+	case "g":
+		p.Emit("", GroupDecl)
+		p.ReadNameList()
+	case "s":
+		p.Emit("", SmoothingDecl)
+		p.ReadSmoothingGroup()
+	case "p":
+		p.Emit("", PointDecl)
+		p.ReadNumberList()
+	case "l":
+		p.Emit("", LineDecl)
+		p.ReadFaceParts()
+	case "vp":
+		p.Emit("", ParamVertexDecl)
+		p.ReadNumberList()
+	case "cstype":
+		p.Emit("", CurveTypeDecl)
+		p.ReadNameList()
+	case "deg":
+		p.Emit("", DegreeDecl)
+		p.ReadNumberList()
+	case "bmat":
+		p.Emit("", BasisMatrixDecl)
+		p.ReadArgList()
+	case "step":
+		p.Emit("", StepDecl)
+		p.ReadNumberList()
+	case "curv":
+		p.Emit("", CurveDecl)
+		p.ReadNumberList()
+	case "curv2":
+		p.Emit("", Curve2DDecl)
+		p.ReadNumberList()
+	case "surf":
+		p.Emit("", SurfaceDecl)
+		p.ReadFaceParts()
+	case "parm":
+		p.Emit("", ParamDecl)
+		p.ReadArgList()
+	case "trim":
+		p.Emit("", TrimDecl)
+		p.ReadNumberList()
+	case "hole":
+		p.Emit("", HoleDecl)
+		p.ReadNumberList()
+	case "scrv":
+		p.Emit("", SpecialCurveDecl)
+		p.ReadNumberList()
+	case "end":
+		p.Emit("", EndDecl)
+	case "ctech":
+		p.Emit("", CurveTechDecl)
+		p.ReadArgList()
+	case "stech":
+		p.Emit("", SurfaceTechDecl)
+		p.ReadArgList()
+	default:
+		p.Emit(keyword, UnknownDecl)
+		// a trailing comment is left to lex, so a \ at its end
+		// doesn't continue the line
+		p.DiscardUntil("\n#")
+		return
+	}
+	p.ReadEndOfLine()
+}
+
+// Read the blank space until the end of the line or a comment
+// and panic if anything else is found
+func (p *Parser) ReadEndOfLine() {
+	p.Discard(" ")
+	p.Mark()
+	if ok, r := p.Peek(""); ok && r != '\n' && r != '#' {
+		p.fail(string(r), fmt.Errorf("%w %q", ErrUnexpectedChar, r))
+	}
+}
+
+// Read the group number or off of a smoothing group statement
+func (p *Parser) ReadSmoothingGroup() {
+	p.Discard(" ")
+	if ok, _ := p.Peek(Number); ok {
+		p.Mark()
+		p.Emit(p.ReadInt(), NumberLit)
+		return
+	}
+	p.ReadNameList()
+}
+
+// Read a list of names separated by spaces until the end of the line
+func (p *Parser) ReadNameList() {
+	p.Discard(" ")
+	for {
+		p.Mark()
+		name := p.AccUntil(" \n#")
+		if len(name) == 0 {
+			return
+		}
+		p.Emit(name, NameLit)
+		p.Discard(" ")
+	}
+}
+
+// Read a list of names, each followed by a list of numbers,
+// until the end of the line (ie, parm u 0.0 1.0)
+func (p *Parser) ReadArgList() {
+	p.ReadNumberList()
+	for {
+		p.Mark()
+		name := p.AccUntil(" \n#")
+		if len(name) == 0 {
+			return
+		}
+		p.Emit(name, NameLit)
+		p.ReadNumberList()
+	}
+}
+
 // Read a variable length list o numbers
 func (p *Parser) ReadNumberList() {
 	p.Discard(" ")
-	for p.NextIf(FloatNumber) {
+	for p.NextIf(NumberStart) {
 		// push the last digit/signal back in the stream
 		p.PushBack()
 		p.ReadNumberLit()
@@ -202,17 +593,19 @@ func (p *Parser) ReadNumberList() {
 }
 
 // Read the x y z[ w] information for a vector
+//
+// Accepts any syntax supported by strconv.ParseFloat, including
+// exponents, hexadecimal floats, a leading + and a missing integer part
 func (p *Parser) ReadNumberLit() {
-	val := ""
-
-	if p.NextIf("-") {
-		val += "-"
+	p.Mark()
+	val := p.AccUntil(NumberEnd)
+	// numbers are stored as float32, so 1e39 is infinite
+	num, err := strconv.ParseFloat(val, 32)
+	if err != nil && err.(*strconv.NumError).Err == strconv.ErrSyntax {
+		p.fail(val, fmt.Errorf("%w %q", ErrInvalidNumber, val))
 	}
-
-	val += p.ReadInt()
-	if p.NextIf(".") {
-		val += "."
-		val += p.ReadInt()
+	if p.NonFinite == RejectNonFinite && (math.IsNaN(num) || math.IsInf(num, 0)) {
+		p.fail(val, fmt.Errorf("%w %q", ErrNonFinite, val))
 	}
 
 	p.Emit(val, NumberLit)
@@ -226,20 +619,22 @@ func (p *Parser) ReadFaceParts() {
 	fn := func() {
 		p.ReadNumberLit()
 
+		p.Mark()
 		if p.NextIf("/") {
 			p.Emit("", SlashLit)
 		}
 
-		if p.NextIf(FloatNumber) {
+		if p.NextIf(NumberStart) {
 			p.PushBack()
 			p.ReadNumberLit()
 		}
 
+		p.Mark()
 		if p.NextIf("/") {
 			p.Emit("", SlashLit)
 		}
 
-		if p.NextIf(FloatNumber) {
+		if p.NextIf(NumberStart) {
 			p.PushBack()
 			p.ReadNumberLit()
 		}
@@ -254,7 +649,7 @@ func (p *Parser) ReadFaceParts() {
 	//
 	// when no numbers are detect
 	// just exit the loop and return for the previous flow
-	for p.NextIf(FloatNumber) {
+	for p.NextIf(NumberStart) {
 		p.PushBack()
 		fn()
 		p.Discard(" ")
@@ -266,38 +661,95 @@ func (p *Parser) ReadFaceParts() {
 func (p *Parser) ReadInt() string {
 	num := p.Acc(Number)
 	if len(num) == 0 {
-		panic("Expecting one of: 0123456789")
+		p.fail(num, fmt.Errorf("%w, expecting one of: 0123456789", ErrInvalidNumber))
 	}
 	return num
 }
 
 // Check if there is more runes in the contents
 func (p *Parser) HasNext() bool {
-	return p.pos < len(p.Contents)
+	ok, _ := p.Peek("")
+	return ok
+}
+
+// Decode the next rune from the reader without consuming it
+//
+// Line endings (\r\n, \n or \r) are returned as a single \n,
+// tabs and other blank characters as a space and a \ followed
+// by a line ending (a line continuation) as a space. Comments
+// are discarded without this, see DiscardComment
+//
+// The size is zero at the end of the input, an invalid utf-8
+// byte is returned as utf8.RuneError with size one
+func (p *Parser) peekRune() (r rune, sz int) {
+	buf, err := p.reader.Peek(utf8.UTFMax)
+	if len(buf) == 0 {
+		if err != io.EOF {
+			panic(err)
+		}
+		return utf8.RuneError, 0
+	}
+	r, sz = utf8.DecodeRune(buf)
+	switch r {
+	case '\r':
+		r = '\n'
+		if len(buf) > 1 && buf[1] == '\n' {
+			sz = 2
+		}
+	case '\t', '\v', '\f':
+		r = ' '
+	case '\\':
+		switch {
+		case len(buf) > 2 && buf[1] == '\r' && buf[2] == '\n':
+			r, sz = ' ', 3
+		case len(buf) > 1 && (buf[1] == '\n' || buf[1] == '\r'):
+			r, sz = ' ', 2
+		}
+	}
+	return
+}
+
+// Check if the last rune was a line continuation
+func (p *Parser) continuation() bool {
+	// the only space with more than one byte
+	return p.C == ' ' && p.sz > 1
 }
 
 // Read the rune and move to the next
 func (p *Parser) Next() bool {
-
-	// EOF
-	if !p.HasNext() {
-		return false
-	}
-	p.C, p.sz = utf8.DecodeRuneInString(p.Contents[p.pos:])
-	if p.C == utf8.RuneError {
-		return false
+	if p.back {
+		p.back = false
+	} else {
+		// EOF
+		if !p.HasNext() {
+			return false
+		}
+		p.C, p.sz = p.peekRune()
+		if p.C == utf8.RuneError && p.sz == 1 {
+			p.raw, _ = p.reader.ReadByte()
+		} else {
+			p.reader.Discard(p.sz)
+		}
 	}
-	p.pos += p.sz
-	// if it is a new line
+	p.oPos = p.cPos
+	// if it is a new line or a line continuation
 	// increment the line number
-	if p.C == '\n' {
-		p.oPos = p.cPos
+	if p.C == '\n' || p.continuation() {
 		p.cPos = Position{p.oPos.Line + 1, 1}
+	} else {
+		p.cPos.Col += 1
 	}
-	p.cPos.Col += 1
 	return true
 }
 
+// Return the text of the last rune read, invalid utf-8 is kept as is
+func (p *Parser) text() string {
+	if p.C == utf8.RuneError && p.sz == 1 {
+		return string([]byte{p.raw})
+	}
+	return string(p.C)
+}
+
 // Read the rune only if it's in the chars
 func (p *Parser) NextIf(chars string) bool {
 	ok, _ := p.Peek(chars)
@@ -310,14 +762,14 @@ func (p *Parser) NextIf(chars string) bool {
 // Peek the next run without consuming it
 func (p *Parser) Peek(chars string) (ok bool, r rune) {
 	ok = true
-	r = utf8.RuneError
-	if !p.HasNext() {
-		ok = false
-		return
+	sz := p.sz
+	if p.back {
+		r = p.C
+	} else {
+		r, sz = p.peekRune()
 	}
-
-	r, _ = utf8.DecodeRuneInString(p.Contents[p.pos:])
-	if r == utf8.RuneError {
+	if sz == 0 {
+		// EOF
 		ok = false
 		return
 	}
@@ -335,25 +787,15 @@ func (p *Parser) Peek(chars string) (ok bool, r rune) {
 
 // Push the last run back in the reader
 func (p *Parser) PushBack() {
-	if p.sz == 0 {
+	if p.back || p.sz == 0 {
 		panic("Cannot push more than one time")
 	}
-	p.pos -= p.sz
-	p.sz = 0
-	// if it was a new line
-	// decrement the line count
-	if p.C == '\n' {
-		p.cPos = p.oPos
-		p.oPos = Position{}
-	}
-	p.C = utf8.RuneError
+	p.back = true
+	p.cPos = p.oPos
 }
 
 // Return a string representation of the current state of the parser
 func (p *Parser) String() string {
-	part := p.Contents[p.pos:]
-	if len(part) > 10 {
-		part = part[:10]
-	}
+	part, _ := p.reader.Peek(10)
 	return fmt.Sprintf("Contents: %q... @ %v", part, p.cPos)
 }
@@ -1,6 +1,7 @@
 package wfobj
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -40,3 +41,210 @@ func TestParser(t *testing.T) {
 		}
 	}
 }
+
+func TestNumberLit(t *testing.T) {
+	valid := []string{"1", "-1", "1.5", "1.5e-07", "+0.25", ".5", "-.5", "5.", "1E3", "0x1p-2"}
+	for _, lit := range valid {
+		p := NewLiteralParser("v " + lit + " 0 0\n")
+		done := make(chan []*Token)
+		go discard(p.Tokens, done, t)
+		err := p.Parse()
+		tokens := <-done
+		if err != nil {
+			t.Errorf("Unable to parse %q. %v", lit, err)
+			continue
+		}
+		if tokens[1].Kind != NumberLit || tokens[1].Val != lit {
+			t.Errorf("Expecting %q but got %v", lit, tokens[1])
+		}
+	}
+
+	invalid := []string{"1.2.3", "1e", "--1", "+", "abc"}
+	for _, lit := range invalid {
+		p := NewLiteralParser("v " + lit + " 0 0\n")
+		done := make(chan []*Token)
+		go discard(p.Tokens, done, t)
+		err := p.Parse()
+		<-done
+		if err == nil {
+			t.Errorf("Expecting an error for %q", lit)
+		}
+	}
+}
+
+func TestNonFinitePolicy(t *testing.T) {
+	for _, lit := range []string{"nan", "inf", "-Inf", "+infinity", "NaN", "1e400", "1e39", "-1e39"} {
+		p := NewLiteralParser("v " + lit + " 0 0\n")
+		done := make(chan []*Token)
+		go discard(p.Tokens, done, t)
+		err := p.Parse()
+		<-done
+		if err == nil {
+			t.Errorf("Expecting %q to be rejected", lit)
+		}
+
+		p = NewLiteralParser("v " + lit + " 0 0\n")
+		p.NonFinite = AllowNonFinite
+		go discard(p.Tokens, done, t)
+		err = p.Parse()
+		<-done
+		if err != nil {
+			t.Errorf("Expecting %q to be allowed. %v", lit, err)
+		}
+	}
+}
+
+func TestNextToken(t *testing.T) {
+	for _, test := range testdata {
+		if test.ignore {
+			continue
+		}
+		p := NewLiteralParser(test.objlit)
+		for i, _ := range test.tokens {
+			tok, err := p.NextToken()
+			if err != nil {
+				t.Fatalf("%v: unable to read token %v. %v", test.title, i, err)
+			}
+			if tok.Kind != test.tokens[i].Kind {
+				t.Fatalf("%v: expecting %v but got %v", test.title, &test.tokens[i], &tok)
+			}
+		}
+		if tok, _ := p.NextToken(); tok.Kind != Eof {
+			t.Errorf("%v: expecting Eof after the end but got %v", test.title, &tok)
+		}
+	}
+
+	p := NewLiteralParser("v 1.0 1.0 1.0\nv 1.0 abc\n")
+	for i := 0; i < 6; i++ {
+		if _, err := p.NextToken(); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := p.NextToken(); err == nil {
+			t.Errorf("Expecting an error for an invalid vertex")
+		}
+	}
+}
+
+func TestTokenPosition(t *testing.T) {
+	p := NewLiteralParser("# comment\nv 1.0  -2 3\nf 1/2/3\n")
+	expected := []Position{{2, 1}, {2, 3}, {2, 8}, {2, 11}, {3, 1}, {3, 3}, {3, 4}, {3, 5}, {3, 6}, {3, 7}, {4, 1}}
+	for i, pos := range expected {
+		tok, err := p.NextToken()
+		if err != nil {
+			t.Fatalf("Unable to read token %v. %v", i, err)
+		}
+		if tok.Pos != pos {
+			t.Errorf("Expecting %v at %v but got %v", &tok, &pos, &tok.Pos)
+		}
+	}
+}
+
+func TestWhitespace(t *testing.T) {
+	p := NewLiteralParser("v\t1.0 \\\r\n  2.0\t3.0 \t\rf 1 \\\n2\\\r3\r\n")
+	expected := []struct {
+		val  string
+		kind Kind
+		pos  Position
+	}{
+		{"", VertexDecl, Position{1, 1}},
+		{"1.0", NumberLit, Position{1, 3}},
+		{"2.0", NumberLit, Position{2, 3}},
+		{"3.0", NumberLit, Position{2, 7}},
+		{"", FaceDecl, Position{3, 1}},
+		{"1", NumberLit, Position{3, 3}},
+		{"2", NumberLit, Position{4, 1}},
+		{"3", NumberLit, Position{5, 1}},
+		{"", Eof, Position{6, 1}},
+	}
+	for i, e := range expected {
+		tok, err := p.NextToken()
+		if err != nil {
+			t.Fatalf("Unable to read token %v. %v", i, err)
+		}
+		if tok.Val != e.val || tok.Kind != e.kind || tok.Pos != e.pos {
+			t.Errorf("Expecting %v %q at %v but got %v at %v", e.kind, e.val, &e.pos, &tok, &tok.Pos)
+		}
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	p := NewLiteralParser("# Cr\xe9\xe9 par\nv 0 0 0\no caf\xe9 \xff\n\xff\n")
+	expected := []Token{
+		{"", VertexDecl, Position{2, 1}},
+		{"0", NumberLit, Position{2, 3}},
+		{"0", NumberLit, Position{2, 5}},
+		{"0", NumberLit, Position{2, 7}},
+		{"", ObjectDecl, Position{3, 1}},
+		{"caf\xe9", NameLit, Position{3, 3}},
+		{"\xff", NameLit, Position{3, 8}},
+		{"\xff", UnknownDecl, Position{4, 1}},
+		{"", Eof, Position{5, 1}},
+	}
+	for i, e := range expected {
+		tok, err := p.NextToken()
+		if err != nil {
+			t.Fatalf("Unable to read token %v. %v", i, err)
+		}
+		if tok != e {
+			t.Errorf("Expecting %v %q at %v but got %v %q at %v", e.Kind, e.Val, &e.Pos, tok.Kind, tok.Val, &tok.Pos)
+		}
+	}
+}
+
+func TestParseErrorCause(t *testing.T) {
+	for _, test := range []struct {
+		lit string
+		err error
+		val string
+	}{
+		{"v 1.0 1.x\n", ErrInvalidNumber, "1.x"},
+		{"v 1.0 abc\n", ErrUnexpectedChar, "a"},
+		{"v 1.0 nan\n", ErrNonFinite, "nan"},
+		{"v 1e39 0 0\n", ErrNonFinite, "1e39"},
+		{"v 1.0 2.0 3.0 )\n", ErrUnexpectedChar, ")"},
+	} {
+		p := NewLiteralParser(test.lit)
+		var err error
+		for err == nil {
+			var tok Token
+			if tok, err = p.NextToken(); tok.Kind == Eof {
+				break
+			}
+		}
+		var perr *ParseError
+		if !errors.As(err, &perr) || !errors.Is(err, test.err) {
+			t.Errorf("Expecting %v for %q but got %v", test.err, test.lit, err)
+			continue
+		}
+		if perr.Val != test.val {
+			t.Errorf("Expecting the offending token %q but got %q", test.val, perr.Val)
+		}
+	}
+}
+
+func TestCommentContinuation(t *testing.T) {
+	p := NewLiteralParser("# exported to C:\\models\\\nv 1 0 0\nfoo bar # C:\\\r\nv 0 1 0 # x\\\rv 0 0 1\n")
+	expected := []struct {
+		kind Kind
+		pos  Position
+	}{
+		{VertexDecl, Position{2, 1}},
+		{UnknownDecl, Position{3, 1}},
+		{VertexDecl, Position{4, 1}},
+		{VertexDecl, Position{5, 1}},
+	}
+	for i, e := range expected {
+		tok, err := p.NextToken()
+		for err == nil && tok.Kind != e.kind && tok.Kind != Eof {
+			tok, err = p.NextToken()
+		}
+		if err != nil || tok.Kind != e.kind {
+			t.Fatalf("Unable to read token %v. %v", i, err)
+		}
+		if tok.Pos != e.pos {
+			t.Errorf("Expecting %v at %v but got %v", e.kind, &e.pos, &tok.Pos)
+		}
+	}
+}
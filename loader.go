@@ -1,68 +1,236 @@
 package wfobj
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 type meshLoader struct {
-	mesh     *Mesh
-	vertices VertexList
-	normals  VertexList
-	tokens   []*Token
-	pos      int
+	// file name used in errors
+	file     string
+	mesh     *IndexedMesh
+	material *Material
+	smooth   int
+	objects  []*Object
+	object   *Object
+	groups   []*Group
+	opts     LoadOptions
+	// position of the mtllib statement of each material library
+	libPos []Position
+	// state set by cstype, deg, bmat and step
+	freeForm FreeForm
+	// state set by ctech and stech
+	curveTech, surfaceTech Technique
+	// element being declared until the end statement, only one is not nil
+	curve   *Curve
+	curve2  *Curve2D
+	surface *Surface
+	tokens  TokenReader
+	// true after the Eof token is read
+	eof bool
+	// tokens read from the stream or pushed back, the last one is the next
+	ahead []*Token
+	// current and previous tokens
+	cur, prev *Token
 }
 
-type MeshLoadError string
+// Source of tokens for the loader, implemented by Parser
+type TokenReader interface {
+	NextToken() (Token, error)
+}
+
+// Adapter to read the tokens sent by Parser.Parse
+//
+// Parse closes the channel without sending Eof when it finds an
+// error, the error itself isn't visible so io.ErrUnexpectedEOF is
+// returned instead
+type chanReader <-chan *Token
+
+func (c chanReader) NextToken() (t Token, err error) {
+	tp, ok := <-c
+	if !ok {
+		t.Kind = Eof
+		err = io.ErrUnexpectedEOF
+		return
+	}
+	t = *tp
+	return
+}
+
+// Returned when a token of an unexpected kind is found
+var ErrUnexpectedToken = errors.New("unexpected token")
+
+// Returned (wrapped in a MeshLoadError) when a statement
+// isn't known and LoadOptions.Strict is set
+var ErrUnknownStatement = errors.New("unknown statement")
+
+// Returned (wrapped in a MeshLoadError) when a name isn't
+// valid utf-8 and LoadOptions.Strict is set
+var ErrInvalidUTF8 = errors.New("invalid utf-8")
+
+// Options used by the LoadOptions methods
+//
+// The zero value skips unknown statements silently, like the
+// Load functions do
+type LoadOptions struct {
+	// Fail on unknown statements and missing material libraries
+	// instead of skipping them
+	Strict bool
+	// Called for each skipped statement or material library
+	// when not strict, may be nil
+	OnWarning func(Warning)
+	// Policy of the parser for NaN and Inf number literals
+	NonFinite NonFinitePolicy
+	// Number of goroutines that lex chunks of the contents in parallel,
+	// zero or one lexes the contents sequentially. The loaded values
+	// and errors are the same, runtime.NumCPU() is a good choice for
+	// large files
+	Workers int
+}
+
+// A problem found while loading that didn't stop the loader
+type Warning struct {
+	// file name, empty if not loading a file
+	File string
+	// position of the statement or of the offending name
+	Pos Position
+	// keyword of the statement, empty for names
+	Statement string
+	Msg       string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%v: %v", positionPrefix(w.File, w.Pos), w.Msg)
+}
+
+// Returned (wrapped in a IndexError) when a face references
+// an element that wasn't declared
+var ErrIndexOutOfRange = errors.New("index out of range")
+
+// A face index that references an element that wasn't declared
+type IndexError struct {
+	// index as written in the file
+	Index int
+	// number of elements declared before the face
+	Size int
+	// vertices, texture coordinates or normals
+	What string
+}
+
+func (e *IndexError) Error() string {
+	return fmt.Sprintf("face index %v out of range (%v %v)", e.Index, e.Size, e.What)
+}
+
+// Make errors.Is(err, ErrIndexOutOfRange) work
+func (e *IndexError) Is(target error) bool {
+	return target == ErrIndexOutOfRange
+}
+
+// An error that happened while loading the tokens of the file
+type MeshLoadError struct {
+	// file name, empty if not loading a file
+	File string
+	// position of the offending token
+	Pos Position
+	// the offending token, nil if none was read
+	Token *Token
+	Err   error
+}
+
+// Return the error for the token
+//
+// If val isn't an error it is converted to one
+func NewMeshLoadError(file string, t *Token, val interface{}) *MeshLoadError {
+	err := &MeshLoadError{File: file, Token: t}
+	if t != nil {
+		err.Pos = t.Pos
+	}
+	if cause, ok := val.(error); ok {
+		err.Err = cause
+	} else {
+		err.Err = errors.New(fmt.Sprintf("%v", val))
+	}
+	return err
+}
 
-func NewMeshLoadError(val interface{}) MeshLoadError {
-	return MeshLoadError(fmt.Sprintf("%v", val))
+func (e *MeshLoadError) Error() string {
+	return fmt.Sprintf("%v: %v", positionPrefix(e.File, e.Pos), e.Err)
 }
-func (m MeshLoadError) Error() string {
-	return "MeshLoadError: " + string(m)
+
+// Return the underlying error
+func (e *MeshLoadError) Unwrap() error {
+	return e.Err
+}
+
+// Return the next token from the lookahead or the stream
+//
+// Return nil if there are no more tokens
+func (m *meshLoader) fetch() *Token {
+	if last := len(m.ahead) - 1; last >= 0 {
+		t := m.ahead[last]
+		m.ahead = m.ahead[:last]
+		return t
+	}
+	if m.eof {
+		return nil
+	}
+	t, err := m.tokens.NextToken()
+	if err != nil {
+		panic(err)
+	}
+	m.eof = t.Kind == Eof
+	return &t
 }
 
 // Read a new token from the parser
 func (m *meshLoader) next() (ok bool) {
-	m.pos++
-	if m.pos >= len(m.tokens) {
+	t := m.fetch()
+	if t == nil {
 		return
 	}
+	m.prev, m.cur = m.cur, t
 	ok = true
 	return
 }
 
 func (m *meshLoader) peek(k Kind) (t *Token, ok bool) {
-	npos := m.pos + 1
-	if npos >= len(m.tokens) {
+	t = m.fetch()
+	if t == nil {
 		return
 	}
-	t = m.tokens[npos]
-	if k != AnyKind {
-		ok = t.Kind == k
-	}
+	m.ahead = append(m.ahead, t)
+	ok = k == AnyKind || t.Kind == k
 	return
 }
 
 // Read the current token
 func (m *meshLoader) token() *Token {
-	if m.pos < 0 {
-		panic("Invalid position. Must be non zero")
-	}
-	if m.pos >= len(m.tokens) {
-		panic("Invalid position. Must be less then length")
+	if m.cur == nil {
+		panic("Invalid position. No token was read")
 	}
-	return m.tokens[m.pos]
+	return m.cur
 }
 
 func (m *meshLoader) ensureKind(k Kind) {
 	if m.token().Kind != k {
-		panic(fmt.Sprintf("Invalid token %v. Expecting %v", m.token(), k))
+		panic(fmt.Errorf("%w %v %q, expecting %v", ErrUnexpectedToken, m.token().Kind, m.token().Val, k))
 	}
 }
 
+// Push the current token back, only the previous token is kept
+// so it can't be called twice in a row
 func (m *meshLoader) pushBack() {
-	m.pos--
+	if m.cur == nil {
+		panic("Cannot push more than one time")
+	}
+	m.ahead = append(m.ahead, m.cur)
+	m.cur, m.prev = m.prev, nil
 }
 
 // Read a number from the token stream and return the number
@@ -76,45 +244,554 @@ func (m *meshLoader) readNumberLit() (num float64) {
 	m.ensureKind(NumberLit)
 
 	num, err := strconv.ParseFloat(t.Val, 64)
-	if err != nil {
+	if err != nil && err.(*strconv.NumError).Err != strconv.ErrRange {
 		panic(err)
 	}
 	return num
 }
 
+// Read an integer from the token stream, panic if the
+// number isn't an integer or doesn't fit in an int
+func (m *meshLoader) readIntLit() int {
+	m.next()
+	t := m.token()
+	m.ensureKind(NumberLit)
+
+	num, err := strconv.Atoi(t.Val)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid integer %q", t.Val))
+	}
+	return num
+}
+
 // Read the face declaration with the number/number/number format
-func (m *meshLoader) readFaceDecl(f *Face) {
+//
+// Accepts the v, v/vt, v//vn and v/vt/vn forms
+func (m *meshLoader) readFaceDecl(f *IndexedFace) {
 	for m.next() {
 		t := m.token()
-		if t.Kind == NumberLit {
+		if t.Kind != NumberLit {
 			m.pushBack()
-			idx := int32(m.readNumberLit())
-			f.Vertices = append(f.Vertices, m.vertices[idx-1])
+			break
+		}
+		m.pushBack()
+		idx := Index{-1, -1, -1}
+		idx.Vertex = m.readIndex(len(m.mesh.Vertices), "vertices")
 
-			// texture information
+		// texture information
+		if _, ok := m.peek(SlashLit); ok {
 			m.next()
-			t = m.token()
-			if t.Kind == SlashLit {
-				// TODO handle textures
-			} else {
-				m.pushBack()
-				continue
+			if _, ok := m.peek(NumberLit); ok {
+				idx.TexCoord = m.readIndex(len(m.mesh.TexCoords), "texture coordinates")
 			}
 
 			// normal information
-			m.next()
-			t = m.token()
-			if t.Kind == SlashLit {
-				idx := int32(m.readNumberLit())
-				f.Normals = append(f.Normals, m.normals[idx-1])
-			} else {
-				m.pushBack()
-				continue
+			if _, ok := m.peek(SlashLit); ok {
+				m.next()
+				idx.Normal = m.readIndex(len(m.mesh.Normals), "normals")
 			}
-		} else {
-			m.pushBack()
+		}
+		f.Indices = append(f.Indices, idx)
+	}
+}
+
+// Read a one based index from the token stream and
+// return it as a zero based index in a list of size elements
+//
+// Negative indices are relative to the end of the list
+func (m *meshLoader) readIndex(size int, what string) int {
+	num := m.readIntLit()
+	idx := num - 1
+	if num < 0 {
+		idx = size + num
+	}
+	if num == 0 || idx < 0 || idx >= size {
+		panic(&IndexError{num, size, what})
+	}
+	return idx
+}
+
+// Read a list of names from the token stream
+func (m *meshLoader) readNameList() (names []string) {
+	for {
+		t, ok := m.peek(NameLit)
+		if !ok {
+			return
+		}
+		m.next()
+		if !utf8.ValidString(t.Val) {
+			m.invalidName()
+		}
+		names = append(names, t.Val)
+	}
+}
+
+// Return the current object, creating one without name if needed
+func (m *meshLoader) currentObject() *Object {
+	if m.object == nil {
+		m.startObject("")
+	}
+	return m.object
+}
+
+// Start a new object and make it the current one
+func (m *meshLoader) startObject(name string) {
+	m.object = &Object{Name: name}
+	m.objects = append(m.objects, m.object)
+	m.groups = nil
+}
+
+// Make the groups with the given names the active ones
+//
+// The default group is used if no name is given
+func (m *meshLoader) useGroups(names []string) {
+	if len(names) == 0 {
+		names = []string{"default"}
+	}
+	obj := m.currentObject()
+	m.groups = make([]*Group, 0, len(names))
+	for _, name := range names {
+		g := obj.Group(name)
+		if g == nil {
+			g = &Group{Name: name}
+			obj.Groups = append(obj.Groups, g)
+		}
+		m.groups = append(m.groups, g)
+	}
+}
+
+// Add the face to the current object and active groups
+func (m *meshLoader) addFace(f IndexedFace) {
+	idx := len(m.mesh.Faces)
+	m.mesh.Faces = append(m.mesh.Faces, f)
+
+	obj := m.currentObject()
+	obj.Ranges = appendFace(obj.Ranges, idx)
+	for _, g := range m.groups {
+		g.Ranges = appendFace(g.Ranges, idx)
+	}
+}
+
+// Read the v1 v2 ... information of a p statement
+func (m *meshLoader) readPoints() {
+	n := 0
+	for {
+		if _, ok := m.peek(NumberLit); !ok {
+			break
+		}
+		m.mesh.Points = append(m.mesh.Points, m.readIndex(len(m.mesh.Vertices), "vertices"))
+		n++
+	}
+	if n == 0 {
+		panic(fmt.Sprintf("Expecting vertices after %v", m.token()))
+	}
+}
+
+// Read the v1/vt1 v2/vt2 ... information of a l statement
+func (m *meshLoader) readLine() {
+	f := IndexedFace{}
+	m.readFaceDecl(&f)
+	if len(f.Indices) < 2 {
+		panic(fmt.Sprintf("Expecting at least two vertices after %v", m.token()))
+	}
+	for _, idx := range f.Indices {
+		if idx.Normal >= 0 {
+			panic("Unexpected normal in a line")
+		}
+	}
+	m.mesh.Lines = append(m.mesh.Lines, IndexedLine{f.Indices, m.material})
+}
+
+// Read the group number or off of a smoothing group statement
+func (m *meshLoader) readSmoothingGroup() int {
+	if _, ok := m.peek(NumberLit); ok {
+		return int(m.readNumberLit())
+	}
+	names := m.readNameList()
+	if len(names) != 1 || names[0] != "off" {
+		panic(fmt.Sprintf("Expecting a smoothing group number or off after %v", m.token()))
+	}
+	return 0
+}
+
+// Read the x y z information of a vertex or normal
+func (m *meshLoader) readVertex() (v Vertex) {
+	v.X = float32(m.readNumberLit())
+	v.Y = float32(m.readNumberLit())
+	v.Z = float32(m.readNumberLit())
+	return
+}
+
+// Read the x y z [w] [r g b] information of a v statement
+//
+// The optional w and colors are stored in the mesh, the vertices
+// declared without them use 1 as w and white as color
+func (m *meshLoader) readPosition() (v Vertex) {
+	v = m.readVertex()
+	extra := make([]float32, 0, 4)
+	for len(extra) < 4 {
+		if _, ok := m.peek(NumberLit); !ok {
 			break
 		}
+		extra = append(extra, float32(m.readNumberLit()))
+	}
+
+	idx := len(m.mesh.Vertices)
+	switch len(extra) {
+	case 0:
+	case 1:
+		m.setWeight(idx, extra[0])
+	case 3:
+		m.setColor(idx, Color{extra[0], extra[1], extra[2]})
+	case 4:
+		m.setWeight(idx, extra[0])
+		m.setColor(idx, Color{extra[1], extra[2], extra[3]})
+	default:
+		panic(fmt.Sprintf("Expecting 3, 4, 6 or 7 numbers after %v", m.token()))
+	}
+	return
+}
+
+// Set the w of the vertex at idx, filling the previous ones with 1
+func (m *meshLoader) setWeight(idx int, w float32) {
+	for len(m.mesh.Weights) < idx {
+		m.mesh.Weights = append(m.mesh.Weights, 1)
+	}
+	m.mesh.Weights = append(m.mesh.Weights, w)
+}
+
+// Set the color of the vertex at idx, filling the previous ones with white
+func (m *meshLoader) setColor(idx int, c Color) {
+	for len(m.mesh.Colors) < idx {
+		m.mesh.Colors = append(m.mesh.Colors, Color{1, 1, 1})
+	}
+	m.mesh.Colors = append(m.mesh.Colors, c)
+}
+
+// Fill the weights and colors of the vertices declared
+// after the last one that had them
+func (m *meshLoader) fillVertexInfo() {
+	for len(m.mesh.Weights) > 0 && len(m.mesh.Weights) < len(m.mesh.Vertices) {
+		m.mesh.Weights = append(m.mesh.Weights, 1)
+	}
+	for len(m.mesh.Colors) > 0 && len(m.mesh.Colors) < len(m.mesh.Vertices) {
+		m.mesh.Colors = append(m.mesh.Colors, Color{1, 1, 1})
+	}
+}
+
+// Read the u [v [w]] information of a texture coordinate
+func (m *meshLoader) readTexCoord() (tc Vertex) {
+	tc.X = float32(m.readNumberLit())
+	if _, ok := m.peek(NumberLit); ok {
+		tc.Y = float32(m.readNumberLit())
+	}
+	if _, ok := m.peek(NumberLit); ok {
+		tc.Z = float32(m.readNumberLit())
+	}
+	return
+}
+
+// Read the u [v [w]] information of a parameter vertex
+func (m *meshLoader) readParamVertex() (v Vertex) {
+	v = Vertex{0, 0, 1}
+	v.X = float32(m.readNumberLit())
+	if _, ok := m.peek(NumberLit); ok {
+		v.Y = float32(m.readNumberLit())
+	}
+	if _, ok := m.peek(NumberLit); ok {
+		v.Z = float32(m.readNumberLit())
+	}
+	return
+}
+
+// Read a list of numbers from the token stream
+func (m *meshLoader) readNumberList() (nums []float32) {
+	for {
+		if _, ok := m.peek(NumberLit); !ok {
+			return
+		}
+		nums = append(nums, float32(m.readNumberLit()))
+	}
+}
+
+// Read one or two integers of a deg or step statement
+func (m *meshLoader) readIntPair() (u, v int) {
+	decl := m.token()
+	var nums []int
+	for {
+		if _, ok := m.peek(NumberLit); !ok {
+			break
+		}
+		nums = append(nums, m.readIntLit())
+	}
+	if len(nums) == 0 || len(nums) > 2 {
+		panic(fmt.Sprintf("Expecting one or two integers after %v", decl))
+	}
+	u = nums[0]
+	if len(nums) == 2 {
+		v = nums[1]
+	}
+	return
+}
+
+// Read the [rat] type information of a cstype statement
+func (m *meshLoader) readCurveType() {
+	names := m.readNameList()
+	rat := len(names) == 2 && names[0] == "rat"
+	if rat {
+		names = names[1:]
+	}
+	if len(names) != 1 {
+		panic(fmt.Sprintf("Expecting a curve type after %v", m.token()))
+	}
+	t, ok := curveTypeNames[names[0]]
+	if !ok {
+		panic(fmt.Sprintf("Unknown curve type %v", names[0]))
+	}
+	m.freeForm.Type, m.freeForm.Rational = t, rat
+}
+
+// Read the u or v direction of a bmat or parm statement
+func (m *meshLoader) readDirection() string {
+	names := m.readNameList()
+	if len(names) != 1 || (names[0] != "u" && names[0] != "v") {
+		panic(fmt.Sprintf("Expecting u or v after %v", m.token()))
+	}
+	return names[0]
+}
+
+// Read the u|v matrix information of a bmat statement
+func (m *meshLoader) readBasisMatrix() {
+	if m.readDirection() == "u" {
+		m.freeForm.BasisU = m.readNumberList()
+	} else {
+		m.freeForm.BasisV = m.readNumberList()
+	}
+}
+
+// Read the technique and arguments of a ctech or stech statement
+func (m *meshLoader) readTechnique() (t Technique) {
+	decl := m.token()
+	surface := decl.Kind == SurfaceTechDecl
+	names := m.readNameList()
+	if len(names) != 1 {
+		panic(fmt.Sprintf("Expecting a technique after %v", decl))
+	}
+	nums := m.readNumberList()
+	args := 0
+	switch {
+	case names[0] == "cparm" && !surface:
+		t.Kind, args = ParamTechnique, 1
+	case names[0] == "cparma" && surface:
+		t.Kind, args = ParamTechnique, 2
+	case names[0] == "cparmb" && surface:
+		t.Kind, args = ParamBTechnique, 1
+	case names[0] == "cspace":
+		t.Kind, args = SpaceTechnique, 1
+	case names[0] == "curv":
+		t.Kind, args = CurvatureTechnique, 2
+	default:
+		panic(fmt.Sprintf("Unknown technique %v", names[0]))
+	}
+	if len(nums) != args {
+		panic(fmt.Sprintf("Expecting %v numbers after %v", args, names[0]))
+	}
+	switch t.Kind {
+	case ParamTechnique:
+		t.ResolutionU = nums[0]
+		t.ResolutionV = nums[len(nums)-1]
+	case ParamBTechnique:
+		t.ResolutionU, t.ResolutionV = nums[0], nums[0]
+	case SpaceTechnique:
+		t.MaxLength = nums[0]
+	case CurvatureTechnique:
+		t.MaxDistance, t.MaxAngle = nums[0], nums[1]
+	}
+	return
+}
+
+// Return the w of the vertex at idx
+func (m *meshLoader) weight(idx int) float32 {
+	if idx < len(m.mesh.Weights) {
+		return m.mesh.Weights[idx]
+	}
+	return 1
+}
+
+// Return the attributes of a new element, panic if
+// the previous element wasn't ended
+func (m *meshLoader) startElement() FreeForm {
+	if m.curve != nil || m.curve2 != nil || m.surface != nil {
+		panic(fmt.Sprintf("Expecting end before %v", m.token()))
+	}
+	ff := m.freeForm
+	ff.ParamsU, ff.ParamsV = nil, nil
+	return ff
+}
+
+// Read the u0 u1 v1 v2 ... information of a curv statement
+func (m *meshLoader) readCurve() {
+	c := &Curve{FreeForm: m.startElement()}
+	c.Technique = m.curveTech
+	c.Start = float32(m.readNumberLit())
+	c.End = float32(m.readNumberLit())
+	for {
+		if _, ok := m.peek(NumberLit); !ok {
+			break
+		}
+		idx := m.readIndex(len(m.mesh.Vertices), "vertices")
+		c.ControlPoints = append(c.ControlPoints, m.mesh.Vertices[idx])
+		c.Weights = append(c.Weights, m.weight(idx))
+	}
+	if len(c.ControlPoints) < 2 {
+		panic(fmt.Sprintf("Expecting at least two control points after %v", m.token()))
+	}
+	m.curve = c
+}
+
+// Read the vp1 vp2 ... information of a curv2 statement
+func (m *meshLoader) readCurve2D() {
+	c := &Curve2D{FreeForm: m.startElement()}
+	params := m.mesh.FreeForm.ParamVertices
+	for {
+		if _, ok := m.peek(NumberLit); !ok {
+			break
+		}
+		idx := m.readIndex(len(params), "parameter vertices")
+		c.ControlPoints = append(c.ControlPoints, params[idx])
+	}
+	if len(c.ControlPoints) < 2 {
+		panic(fmt.Sprintf("Expecting at least two control points after %v", m.token()))
+	}
+	m.curve2 = c
+}
+
+// Read the s0 s1 t0 t1 v1/vt1/vn1 ... information of a surf statement
+func (m *meshLoader) readSurface() {
+	s := &Surface{FreeForm: m.startElement()}
+	s.Technique = m.surfaceTech
+	s.StartU = float32(m.readNumberLit())
+	s.EndU = float32(m.readNumberLit())
+	s.StartV = float32(m.readNumberLit())
+	s.EndV = float32(m.readNumberLit())
+	s.Material = m.material
+
+	f := IndexedFace{}
+	m.readFaceDecl(&f)
+	if len(f.Indices) == 0 {
+		panic(fmt.Sprintf("Expecting control points after %v", m.token()))
+	}
+	texcoords, normals := true, true
+	for _, idx := range f.Indices {
+		texcoords = texcoords && idx.TexCoord >= 0
+		normals = normals && idx.Normal >= 0
+	}
+	for _, idx := range f.Indices {
+		s.ControlPoints = append(s.ControlPoints, m.mesh.Vertices[idx.Vertex])
+		s.Weights = append(s.Weights, m.weight(idx.Vertex))
+		if texcoords {
+			s.TexCoords = append(s.TexCoords, m.mesh.TexCoords[idx.TexCoord])
+		}
+		if normals {
+			s.Normals = append(s.Normals, m.mesh.Normals[idx.Normal])
+		}
+	}
+	m.surface = s
+}
+
+// Return the attributes of the element being declared
+func (m *meshLoader) element() *FreeForm {
+	switch {
+	case m.curve != nil:
+		return &m.curve.FreeForm
+	case m.curve2 != nil:
+		return &m.curve2.FreeForm
+	case m.surface != nil:
+		return &m.surface.FreeForm
+	}
+	panic(fmt.Sprintf("Unexpected %v outside of a curve or surface", m.token()))
+}
+
+// Read the u|v p1 p2 ... information of a parm statement
+func (m *meshLoader) readParam() {
+	ff := m.element()
+	if m.readDirection() == "u" {
+		ff.ParamsU = m.readNumberList()
+		return
+	}
+	if m.surface == nil {
+		panic("Unexpected parm v outside of a surface")
+	}
+	ff.ParamsV = m.readNumberList()
+}
+
+// Read the u0 u1 curv2d ... information of a trim, hole or scrv statement
+// and add the loop to the surface being declared
+func (m *meshLoader) readLoop() {
+	if m.surface == nil {
+		panic(fmt.Sprintf("Unexpected %v outside of a surface", m.token()))
+	}
+	kind := m.token().Kind
+	loop := Loop{}
+	for {
+		if _, ok := m.peek(NumberLit); !ok {
+			break
+		}
+		ref := CurveRef{}
+		ref.Start = float32(m.readNumberLit())
+		ref.End = float32(m.readNumberLit())
+		ref.Curve = m.readIndex(len(m.mesh.FreeForm.Curves2D), "curves")
+		loop = append(loop, ref)
+	}
+	if len(loop) == 0 {
+		panic(fmt.Sprintf("Expecting curves after %v", m.token()))
+	}
+	switch kind {
+	case TrimDecl:
+		m.surface.Trims = append(m.surface.Trims, loop)
+	case HoleDecl:
+		m.surface.Holes = append(m.surface.Holes, loop)
+	default:
+		m.surface.SpecialCurves = append(m.surface.SpecialCurves, loop)
+	}
+}
+
+// Add the element being declared to the mesh
+func (m *meshLoader) endElement() {
+	ff := &m.mesh.FreeForm
+	switch {
+	case m.curve != nil:
+		ff.Curves = append(ff.Curves, *m.curve)
+	case m.curve2 != nil:
+		ff.Curves2D = append(ff.Curves2D, *m.curve2)
+	case m.surface != nil:
+		ff.Surfaces = append(ff.Surfaces, *m.surface)
+	default:
+		panic(fmt.Sprintf("Unexpected %v outside of a curve or surface", m.token()))
+	}
+	m.curve, m.curve2, m.surface = nil, nil, nil
+}
+
+// Fail or warn about a name that isn't valid utf-8, the
+// name is used as is when not strict
+func (m *meshLoader) invalidName() {
+	t := m.token()
+	if m.opts.Strict {
+		panic(fmt.Errorf("%w in name %q", ErrInvalidUTF8, t.Val))
+	}
+	if m.opts.OnWarning != nil {
+		m.opts.OnWarning(Warning{m.file, t.Pos, "", fmt.Sprintf("invalid utf-8 in name %q", t.Val)})
+	}
+}
+
+// Fail or warn about a statement that isn't known
+func (m *meshLoader) unknownStatement() {
+	t := m.token()
+	if m.opts.Strict {
+		panic(fmt.Errorf("%w %q", ErrUnknownStatement, t.Val))
+	}
+	if m.opts.OnWarning != nil {
+		m.opts.OnWarning(Warning{m.file, t.Pos, t.Val, fmt.Sprintf("ignored unknown statement %q", t.Val)})
 	}
 }
 
@@ -122,63 +799,315 @@ func (m *meshLoader) Load() (err error) {
 
 	defer func() {
 		if p := recover(); p != nil {
-			err = NewMeshLoadError(p)
+			if perr, ok := p.(*ParseError); ok {
+				err = perr
+				return
+			}
+			err = NewMeshLoadError(m.file, m.cur, p)
 		}
 	}()
 
-	m.vertices = make(VertexList, 0)
-	m.normals = make(VertexList, 0)
-	m.mesh = &Mesh{}
-	m.mesh.Faces = make([]Face, 0)
+	m.mesh = &IndexedMesh{}
+	m.mesh.Vertices = make(VertexList, 0)
+	m.mesh.Normals = make(VertexList, 0)
+	m.mesh.TexCoords = make(VertexList, 0)
+	m.mesh.Weights = make([]float32, 0)
+	m.mesh.Colors = make([]Color, 0)
+	m.mesh.Faces = make([]IndexedFace, 0)
+	m.mesh.Points = make([]int, 0)
+	m.mesh.Lines = make([]IndexedLine, 0)
+	m.mesh.MaterialLibs = make([]string, 0)
+	m.mesh.Materials = make(map[string]*Material)
+	m.objects = make([]*Object, 0)
 
 	for m.next() {
 		switch m.token().Kind {
 		case VertexDecl:
-			v := Vertex{}
-			v.X = float32(m.readNumberLit())
-			v.Y = float32(m.readNumberLit())
-			v.Z = float32(m.readNumberLit())
-			m.vertices = append(m.vertices, v)
+			m.mesh.Vertices = append(m.mesh.Vertices, m.readPosition())
 		case NormalDecl:
-			n := Vertex{}
-			n.X = float32(m.readNumberLit())
-			n.Y = float32(m.readNumberLit())
-			n.Z = float32(m.readNumberLit())
-			m.normals = append(m.normals, n)
+			m.mesh.Normals = append(m.mesh.Normals, m.readVertex())
+		case TexCoordDecl:
+			m.mesh.TexCoords = append(m.mesh.TexCoords, m.readTexCoord())
 		case FaceDecl:
-			f := Face{}
-			f.Vertices = make(VertexList, 0)
-			f.Normals = make(VertexList, 0)
+			f := IndexedFace{}
+			f.Indices = make([]Index, 0)
+			f.Material = m.material
+			f.SmoothingGroup = m.smooth
 			m.readFaceDecl(&f)
-			m.mesh.Faces = append(m.mesh.Faces, f)
+			m.addFace(f)
+		case PointDecl:
+			m.readPoints()
+		case LineDecl:
+			m.readLine()
+		case MaterialLibDecl:
+			pos := m.token().Pos
+			for _, lib := range m.readNameList() {
+				m.mesh.MaterialLibs = append(m.mesh.MaterialLibs, lib)
+				m.libPos = append(m.libPos, pos)
+			}
+		case UseMaterialDecl:
+			names := m.readNameList()
+			if len(names) == 0 {
+				panic(fmt.Sprintf("Expecting a material name after %v", m.token()))
+			}
+			m.material = m.mesh.Material(strings.Join(names, " "))
+		case SmoothingDecl:
+			m.smooth = m.readSmoothingGroup()
+		case ObjectDecl:
+			m.startObject(strings.Join(m.readNameList(), " "))
+		case GroupDecl:
+			m.useGroups(m.readNameList())
+		case ParamVertexDecl:
+			m.mesh.FreeForm.ParamVertices = append(m.mesh.FreeForm.ParamVertices, m.readParamVertex())
+		case CurveTypeDecl:
+			m.readCurveType()
+		case DegreeDecl:
+			m.freeForm.DegreeU, m.freeForm.DegreeV = m.readIntPair()
+		case BasisMatrixDecl:
+			m.readBasisMatrix()
+		case StepDecl:
+			m.freeForm.StepU, m.freeForm.StepV = m.readIntPair()
+		case CurveDecl:
+			m.readCurve()
+		case Curve2DDecl:
+			m.readCurve2D()
+		case SurfaceDecl:
+			m.readSurface()
+		case ParamDecl:
+			m.readParam()
+		case TrimDecl, HoleDecl, SpecialCurveDecl:
+			m.readLoop()
+		case EndDecl:
+			m.endElement()
+		case CurveTechDecl:
+			m.curveTech = m.readTechnique()
+		case SurfaceTechDecl:
+			m.surfaceTech = m.readTechnique()
+		case UnknownDecl:
+			m.unknownStatement()
 		case Eof:
-			break
+			if m.curve != nil || m.curve2 != nil || m.surface != nil {
+				panic("Expecting end before the end of the file")
+			}
 		default:
-			panic(fmt.Sprintf("Unexpected token (%v) expecting: %v", m.token(), fmt.Sprintf("[%v]", []Kind{VertexDecl, FaceDecl, Eof})))
+			panic(fmt.Errorf("%w %v %q", ErrUnexpectedToken, m.token().Kind, m.token().Val))
 		}
 	}
+	m.fillVertexInfo()
 
 	return
 }
 
-// Load a new mesh
-func LoadMesh(tokens <-chan *Token) (m *Mesh, err error) {
-	ml := &meshLoader{nil, nil, nil, make([]*Token, 0), -1}
-	for t := range tokens {
-		ml.tokens = append(ml.tokens, t)
+// Run the loader consuming the tokens as they are needed
+func load(tokens TokenReader, opts LoadOptions) (ml *meshLoader, err error) {
+	ml = &meshLoader{opts: opts, tokens: tokens, ahead: make([]*Token, 0, 2)}
+	switch t := tokens.(type) {
+	case *Parser:
+		ml.file = t.File
+	case *chunkReader:
+		ml.file = t.file
 	}
 	err = ml.Load()
+	return
+}
+
+// Run the loader with the tokens sent by Parser.Parse
+func loadChan(tokens <-chan *Token) (ml *meshLoader, err error) {
+	ml, err = load(chanReader(tokens), LoadOptions{})
+	if err != nil {
+		// let the parser finish
+		for _ = range tokens {
+		}
+	}
+	return
+}
+
+// Parse the file, run the loader and bind the material libraries
+func loadFile(file string, opts LoadOptions) (ml *meshLoader, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	tokens, release := opts.tokens(f, file)
+	defer release()
+	ml, err = load(tokens, opts)
+	if err != nil {
+		return
+	}
+	err = ml.loadMaterialLibs(filepath.Dir(file))
+	return
+}
+
+// Load a new indexed mesh
+func LoadIndexedMesh(tokens <-chan *Token) (m *IndexedMesh, err error) {
+	ml, err := loadChan(tokens)
+	m = ml.mesh
+	return
+}
+
+// Load a new indexed mesh reading the .obj contents from r
+func LoadIndexedMeshFromReader(r io.Reader) (m *IndexedMesh, err error) {
+	return LoadOptions{}.LoadIndexedMesh(r)
+}
+
+// Parse the contents of r and run the loader
+func (o LoadOptions) load(r io.Reader) (ml *meshLoader, err error) {
+	tokens, release := o.tokens(r, "")
+	defer release()
+	return load(tokens, o)
+}
+
+// Return the source of the tokens of r and a function to release it
+//
+// file is only used in errors
+func (o LoadOptions) tokens(r io.Reader, file string) (TokenReader, func()) {
+	if o.Workers > 1 {
+		c := newChunkReader(r, file, o.Workers, chunkSize, o.NonFinite)
+		return c, c.Close
+	}
+	p := NewParser(r)
+	p.NonFinite = o.NonFinite
+	p.File = file
+	return p, func() {}
+}
+
+// Load a new indexed mesh reading the .obj contents from r
+func (o LoadOptions) LoadIndexedMesh(r io.Reader) (m *IndexedMesh, err error) {
+	ml, err := o.load(r)
+	m = ml.mesh
+	return
+}
+
+// Load a new indexed mesh from the given .obj file
+//
+// Material libraries are resolved relative to the directory of the file,
+// missing libraries are ignored and leave the materials with default values
+func LoadIndexedMeshFromFile(file string) (m *IndexedMesh, err error) {
+	return LoadOptions{}.LoadIndexedMeshFromFile(file)
+}
+
+// Load a new indexed mesh from the given .obj file
+//
+// See LoadIndexedMeshFromFile
+func (o LoadOptions) LoadIndexedMeshFromFile(file string) (m *IndexedMesh, err error) {
+	ml, err := loadFile(file, o)
+	if err != nil {
+		return
+	}
 	m = ml.mesh
 	return
 }
 
+// Load a new mesh reading the .obj contents from r
+//
+// See NewParser
+func LoadMeshFromReader(r io.Reader) (m *Mesh, err error) {
+	return LoadOptions{}.LoadMesh(r)
+}
+
+// Load a new mesh reading the .obj contents from r
+func (o LoadOptions) LoadMesh(r io.Reader) (m *Mesh, err error) {
+	ml, err := o.load(r)
+	if err != nil {
+		return
+	}
+	m = ml.mesh.Mesh()
+	return
+}
+
+// Load a new mesh
+func LoadMesh(tokens <-chan *Token) (m *Mesh, err error) {
+	im, err := LoadIndexedMesh(tokens)
+	if err != nil {
+		return
+	}
+	m = im.Mesh()
+	return
+}
+
 // Load a new mesh from the given .obj file
+//
+// See LoadIndexedMeshFromFile for how material libraries are resolved
 func LoadMeshFromFile(file string) (m *Mesh, err error) {
-	p, err := NewParserFromFile(file)
+	return LoadOptions{}.LoadMeshFromFile(file)
+}
+
+// Load a new mesh from the given .obj file
+//
+// See LoadIndexedMeshFromFile
+func (o LoadOptions) LoadMeshFromFile(file string) (m *Mesh, err error) {
+	im, err := o.LoadIndexedMeshFromFile(file)
+	if err != nil {
+		return
+	}
+	m = im.Mesh()
+	return
+}
+
+// Load a new model with its objects and groups
+func LoadModel(tokens <-chan *Token) (m *Model, err error) {
+	ml, err := loadChan(tokens)
 	if err != nil {
 		return
 	}
-	go p.Parse()
-	m, err = LoadMesh(p.Tokens)
+	m = &Model{ml.mesh.Mesh(), ml.objects}
 	return
 }
+
+// Load a new model reading the .obj contents from r
+func LoadModelFromReader(r io.Reader) (m *Model, err error) {
+	return LoadOptions{}.LoadModel(r)
+}
+
+// Load a new model reading the .obj contents from r
+func (o LoadOptions) LoadModel(r io.Reader) (m *Model, err error) {
+	ml, err := o.load(r)
+	if err != nil {
+		return
+	}
+	m = &Model{ml.mesh.Mesh(), ml.objects}
+	return
+}
+
+// Load a new model from the given .obj file
+//
+// See LoadIndexedMeshFromFile for how material libraries are resolved
+func LoadModelFromFile(file string) (m *Model, err error) {
+	return LoadOptions{}.LoadModelFromFile(file)
+}
+
+// Load a new model from the given .obj file
+//
+// See LoadIndexedMeshFromFile
+func (o LoadOptions) LoadModelFromFile(file string) (m *Model, err error) {
+	ml, err := loadFile(file, o)
+	if err != nil {
+		return
+	}
+	m = &Model{ml.mesh.Mesh(), ml.objects}
+	return
+}
+
+// Load and bind the material libraries referenced by the mesh
+//
+// Missing libraries fail when strict, otherwise they are skipped
+func (m *meshLoader) loadMaterialLibs(dir string) error {
+	for i, lib := range m.mesh.MaterialLibs {
+		mats, err := LoadMaterialsFromFile(filepath.Join(dir, lib))
+		if os.IsNotExist(err) {
+			if m.opts.Strict {
+				return &MeshLoadError{File: m.file, Pos: m.libPos[i], Err: err}
+			}
+			if m.opts.OnWarning != nil {
+				m.opts.OnWarning(Warning{m.file, m.libPos[i], "mtllib", fmt.Sprintf("ignored missing material library %q", lib)})
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		m.mesh.BindMaterials(mats)
+	}
+	return nil
+}
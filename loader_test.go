@@ -1,9 +1,22 @@
 package wfobj
 
 import (
+	"errors"
+	"io"
+	"os"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
+// Return the name of the material or an empty string if nil
+func materialName(m *Material) string {
+	if m == nil {
+		return ""
+	}
+	return m.Name
+}
+
 func TestMeshLoader(t *testing.T) {
 	for _, test := range testdata {
 		if test.ignore {
@@ -30,6 +43,485 @@ func TestMeshLoader(t *testing.T) {
 				t.Fatalf("Faces normals are different. Expecting %v got %v", test.mesh.Faces[i].Normals,
 					m.Faces[i].Normals)
 			}
+			if !m.Faces[i].TexCoords.Same(test.mesh.Faces[i].TexCoords) {
+				t.Fatalf("Faces texture coordinates are different. Expecting %v got %v", test.mesh.Faces[i].TexCoords,
+					m.Faces[i].TexCoords)
+			}
+			if materialName(m.Faces[i].Material) != materialName(test.mesh.Faces[i].Material) {
+				t.Fatalf("Faces materials are different. Expecting %v got %v", materialName(test.mesh.Faces[i].Material),
+					materialName(m.Faces[i].Material))
+			}
+			if m.Faces[i].SmoothingGroup != test.mesh.Faces[i].SmoothingGroup {
+				t.Fatalf("Faces smoothing groups are different. Expecting %v got %v", test.mesh.Faces[i].SmoothingGroup,
+					m.Faces[i].SmoothingGroup)
+			}
+		}
+	}
+}
+
+func TestMeshLoaderFromFile(t *testing.T) {
+	m, err := LoadMeshFromFile("cube.obj")
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	if len(m.Faces) != 6 {
+		t.Fatalf("Expecting 6 faces but got %v", len(m.Faces))
+	}
+	for i, _ := range m.Faces {
+		if len(m.Faces[i].Vertices) != 4 {
+			t.Errorf("Expecting 4 vertices in face %v but got %v", i, m.Faces[i].Vertices)
+		}
+	}
+}
+
+func TestMeshLoaderMaterialLibs(t *testing.T) {
+	m, err := LoadMeshFromFile("testdata/materials/materials.obj")
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	if len(m.MaterialLibs) != 2 {
+		t.Fatalf("Expecting 2 material libraries but got %v", m.MaterialLibs)
+	}
+	red := m.Faces[0].Material
+	if red == nil || red.Name != "Red" || red.Diffuse != (Color{1, 0, 0}) {
+		t.Errorf("Expecting the Red material from materials.mtl but got %v", red)
+	}
+	missing := m.Faces[1].Material
+	if missing == nil || missing.Name != "Missing" || missing.Dissolve != 1 {
+		t.Errorf("Expecting a default Missing material but got %v", missing)
+	}
+
+	var warnings []Warning
+	opts := LoadOptions{OnWarning: func(w Warning) { warnings = append(warnings, w) }}
+	if _, err = opts.LoadMeshFromFile("testdata/materials/materials.obj"); err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	expected := "testdata/materials/materials.obj:2:1: ignored missing material library \"missing.mtl\""
+	if len(warnings) != 1 || warnings[0].String() != expected || warnings[0].Statement != "mtllib" {
+		t.Errorf("Expecting the warning %q got %v", expected, warnings)
+	}
+
+	opts = LoadOptions{Strict: true}
+	_, err = opts.LoadMeshFromFile("testdata/materials/materials.obj")
+	var lerr *MeshLoadError
+	if !errors.As(err, &lerr) || !errors.Is(err, os.ErrNotExist) || lerr.Pos != (Position{2, 1}) {
+		t.Errorf("Expecting a MeshLoadError for missing.mtl at 2:1 got %v", err)
+	}
+}
+
+func TestIndexedMeshLoader(t *testing.T) {
+	p := NewLiteralParser(`v 0.0 0.0 0.0
+v 1.0 0.0 0.0
+v 0.0 1.0 0.0
+v 1.0 1.0 0.0
+vt 0.0 0.0
+vn 0.0 0.0 1.0
+f 1/1/1 2/1/1 3/1/1
+f 2//1 4//1 3//1
+`)
+	go p.Parse()
+
+	m, err := LoadIndexedMesh(p.Tokens)
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	if len(m.Vertices) != 4 || len(m.TexCoords) != 1 || len(m.Normals) != 1 {
+		t.Fatalf("Invalid pools. Vertices: %v TexCoords: %v Normals: %v", m.Vertices, m.TexCoords, m.Normals)
+	}
+	expected := [][]Index{
+		{{0, 0, 0}, {1, 0, 0}, {2, 0, 0}},
+		{{1, -1, 0}, {3, -1, 0}, {2, -1, 0}},
+	}
+	if len(m.Faces) != len(expected) {
+		t.Fatalf("Expecting %v faces but got %v", len(expected), len(m.Faces))
+	}
+	for i, _ := range expected {
+		if len(m.Faces[i].Indices) != len(expected[i]) {
+			t.Fatalf("Expecting indices %v got %v", expected[i], m.Faces[i].Indices)
+		}
+		for j, idx := range expected[i] {
+			if m.Faces[i].Indices[j] != idx {
+				t.Errorf("Expecting indices %v got %v", expected[i], m.Faces[i].Indices)
+			}
+		}
+	}
+
+	mesh := m.Mesh()
+	if !mesh.Faces[1].Vertices.Same(VertexList{m.Vertices[1], m.Vertices[3], m.Vertices[2]}) {
+		t.Errorf("Invalid expanded face %v", mesh.Faces[1])
+	}
+	if len(mesh.Faces[1].TexCoords) != 0 || len(mesh.Faces[1].Normals) != 3 {
+		t.Errorf("Invalid expanded face %v", mesh.Faces[1])
+	}
+}
+
+func TestModelLoader(t *testing.T) {
+	p := NewLiteralParser(`v 0.0 0.0 0.0
+v 1.0 0.0 0.0
+v 0.0 1.0 0.0
+f 1 2 3
+o Hull
+g left right
+f 1 2 3
+g left
+f 3 2 1
+o Wing
+g default
+f 2 3 1
+g
+f 1 3 2
+`)
+	go p.Parse()
+
+	m, err := LoadModel(p.Tokens)
+	if err != nil {
+		t.Fatalf("Unable to load model: %v", err)
+	}
+	if len(m.Mesh.Faces) != 5 {
+		t.Fatalf("Expecting 5 faces but got %v", len(m.Mesh.Faces))
+	}
+	if len(m.Objects) != 3 {
+		t.Fatalf("Expecting 3 objects but got %v", len(m.Objects))
+	}
+
+	expected := []struct {
+		object string
+		group  string
+		ranges []FaceRange
+	}{
+		{"", "", []FaceRange{{0, 1}}},
+		{"Hull", "", []FaceRange{{1, 3}}},
+		{"Hull", "left", []FaceRange{{1, 3}}},
+		{"Hull", "right", []FaceRange{{1, 2}}},
+		{"Wing", "", []FaceRange{{3, 5}}},
+		{"Wing", "default", []FaceRange{{3, 5}}},
+	}
+	for _, e := range expected {
+		obj := m.Object(e.object)
+		if obj == nil {
+			t.Fatalf("Object %q not found", e.object)
+		}
+		ranges := obj.Ranges
+		if e.group != "" {
+			g := obj.Group(e.group)
+			if g == nil {
+				t.Fatalf("Group %q not found in %q", e.group, e.object)
+			}
+			ranges = g.Ranges
+		}
+		if len(ranges) != len(e.ranges) {
+			t.Fatalf("%q/%q: expecting ranges %v got %v", e.object, e.group, e.ranges, ranges)
+		}
+		for i, _ := range ranges {
+			if ranges[i] != e.ranges[i] {
+				t.Errorf("%q/%q: expecting ranges %v got %v", e.object, e.group, e.ranges, ranges)
+			}
+		}
+	}
+
+	sub := m.SubMesh(m.Object("Hull").Ranges)
+	if len(sub.Faces) != 2 || !sub.Faces[1].Same(&m.Mesh.Faces[2]) {
+		t.Errorf("Invalid sub mesh %v", sub.Faces)
+	}
+}
+
+func TestMeshLoaderRelativeIndices(t *testing.T) {
+	p := NewLiteralParser(`v 0.0 0.0 0.0
+v 1.0 0.0 0.0
+v 0.0 1.0 0.0
+vt 0.5 0.5
+vn 0.0 0.0 1.0
+f -3/-1/-1 -2/-1/-1 -1/-1/-1
+v 1.0 1.0 0.0
+f 2 -1 -2
+`)
+	go p.Parse()
+
+	m, err := LoadIndexedMesh(p.Tokens)
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	expected := [][]Index{
+		{{0, 0, 0}, {1, 0, 0}, {2, 0, 0}},
+		{{1, -1, -1}, {3, -1, -1}, {2, -1, -1}},
+	}
+	for i, _ := range expected {
+		for j, idx := range expected[i] {
+			if m.Faces[i].Indices[j] != idx {
+				t.Errorf("Expecting indices %v got %v", expected[i], m.Faces[i].Indices)
+			}
+		}
+	}
+}
+
+func TestMeshLoaderVertexInfo(t *testing.T) {
+	p := NewLiteralParser(`v 0.0 0.0 0.0
+v 1.0 0.0 0.0 0.5
+v 0.0 1.0 0.0 1.0 0.0 0.0
+v 1.0 1.0 0.0 2.0 0.0 1.0 0.0
+f 1 2 3 4
+`)
+	go p.Parse()
+
+	m, err := LoadIndexedMesh(p.Tokens)
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	weights := []float32{1, 0.5, 1, 2}
+	colors := []Color{{1, 1, 1}, {1, 1, 1}, {1, 0, 0}, {0, 1, 0}}
+	if len(m.Weights) != len(weights) || len(m.Colors) != len(colors) {
+		t.Fatalf("Expecting %v weights and colors got %v and %v", len(weights), len(m.Weights), len(m.Colors))
+	}
+	for i, _ := range weights {
+		if m.Weights[i] != weights[i] || m.Colors[i] != colors[i] {
+			t.Errorf("Expecting %v %v at %v got %v %v", weights[i], colors[i], i, m.Weights[i], m.Colors[i])
+		}
+	}
+	f := m.Mesh().Faces[0]
+	if len(f.Weights) != 4 || f.Weights[1] != 0.5 || len(f.Colors) != 4 || f.Colors[3] != colors[3] {
+		t.Errorf("Invalid face weights %v or colors %v", f.Weights, f.Colors)
+	}
+
+	p = NewLiteralParser("v 0.0 0.0 0.0 1.0 1.0\n")
+	go p.Parse()
+	if _, err = LoadIndexedMesh(p.Tokens); err == nil {
+		t.Errorf("Vertices with 5 numbers must fail")
+	}
+}
+
+func TestMeshLoaderPointsAndLines(t *testing.T) {
+	p := NewLiteralParser(`v 0.0 0.0 0.0
+v 1.0 0.0 0.0
+v 1.0 1.0 0.0
+vt 0.0 0.0
+vt 1.0 0.0
+p 1 -1
+usemtl wire
+l 1 2 3 1
+l 1/1 2/2
+`)
+	go p.Parse()
+
+	m, err := LoadMesh(p.Tokens)
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	if len(m.Faces) != 0 {
+		t.Errorf("Expecting no faces got %v", len(m.Faces))
+	}
+	if !m.Points.Same(VertexList{{0, 0, 0}, {1, 1, 0}}) {
+		t.Errorf("Invalid points %v", m.Points)
+	}
+	if len(m.Lines) != 2 {
+		t.Fatalf("Expecting 2 lines got %v", len(m.Lines))
+	}
+	if !m.Lines[0].Vertices.Same(VertexList{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 0, 0}}) || len(m.Lines[0].TexCoords) != 0 {
+		t.Errorf("Invalid line %v", m.Lines[0])
+	}
+	if !m.Lines[1].TexCoords.Same(VertexList{{0, 0, 0}, {1, 0, 0}}) {
+		t.Errorf("Invalid line texture coordinates %v", m.Lines[1].TexCoords)
+	}
+	if materialName(m.Lines[1].Material) != "wire" {
+		t.Errorf("Expecting material wire got %v", materialName(m.Lines[1].Material))
+	}
+
+	for _, lit := range []string{"v 0 0 0\nl 1\n", "v 0 0 0\nvn 0 0 1\nl 1//1 1//1\n", "p\n", "p 1\n"} {
+		p = NewLiteralParser(lit)
+		go p.Parse()
+		if _, err = LoadMesh(p.Tokens); err == nil {
+			t.Errorf("Loading %q must fail", lit)
+		}
+	}
+}
+
+func TestLoadOptions(t *testing.T) {
+	lit := `v 0.0 0.0 0.0
+v 1.0 0.0 0.0
+v 0.0 1.0 0.0
+shadow_obj shadow.obj
+f 1 2 3
+  lod 1
+`
+	warnings := make([]Warning, 0)
+	m, err := LoadOptions{OnWarning: func(w Warning) { warnings = append(warnings, w) }}.LoadMesh(strings.NewReader(lit))
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	if len(m.Faces) != 1 {
+		t.Errorf("Expecting 1 face got %v", len(m.Faces))
+	}
+	expected := []string{"4:1: ignored unknown statement \"shadow_obj\"", "6:3: ignored unknown statement \"lod\""}
+	if len(warnings) != len(expected) {
+		t.Fatalf("Expecting %v warnings got %v", len(expected), warnings)
+	}
+	for i, w := range warnings {
+		if w.String() != expected[i] {
+			t.Errorf("Expecting %q got %q", expected[i], w.String())
+		}
+	}
+	if warnings[0].Statement != "shadow_obj" {
+		t.Errorf("Expecting statement shadow_obj got %v", warnings[0].Statement)
+	}
+
+	_, err = LoadOptions{Strict: true}.LoadMesh(strings.NewReader(lit))
+	if !errors.Is(err, ErrUnknownStatement) {
+		t.Fatalf("Expecting ErrUnknownStatement got %v", err)
+	}
+	if err.Error() != "4:1: unknown statement \"shadow_obj\"" {
+		t.Errorf("Invalid error message %q", err.Error())
+	}
+
+	// the only problem of cube.obj is its missing material library
+	strict := LoadOptions{Strict: true}
+	_, err = strict.LoadMeshFromFile("cube.obj")
+	if !errors.Is(err, os.ErrNotExist) || !strings.HasPrefix(err.Error(), "cube.obj:3:1: ") {
+		t.Errorf("Expecting the missing library of cube.obj in strict mode got %v", err)
+	}
+}
+
+func TestMeshLoaderIndexOutOfRange(t *testing.T) {
+	for _, lit := range []string{
+		"v 0.0 0.0 0.0\nf 1 2\n",
+		"v 0.0 0.0 0.0\nf 1 -2\n",
+		"v 0.0 0.0 0.0\nf 0\n",
+		"v 0.0 0.0 0.0\nf 1/1\n",
+		"v 0.0 0.0 0.0\nf 1//-1\n",
+	} {
+		p := NewLiteralParser(lit)
+		go p.Parse()
+		_, err := LoadMesh(p.Tokens)
+		if err == nil {
+			t.Errorf("Expecting an error for %q", lit)
+		}
+	}
+}
+
+func TestMeshLoaderInvalidIndex(t *testing.T) {
+	for _, test := range []struct {
+		lit string
+		pos Position
+	}{
+		{"v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1.7 2.2 3.9\n", Position{4, 3}},
+		{"v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 1e1 3\n", Position{4, 5}},
+		{"v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 99999999999999999999 3\n", Position{4, 5}},
+		{"v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2/1.5 3\n", Position{4, 7}},
+	} {
+		_, err := LoadMeshFromReader(strings.NewReader(test.lit))
+		var lerr *MeshLoadError
+		if !errors.As(err, &lerr) || lerr.Pos != test.pos {
+			t.Errorf("Expecting a MeshLoadError at %v for %q but got %v", &test.pos, test.lit, err)
+		}
+	}
+}
+
+func TestMeshLoaderFromReader(t *testing.T) {
+	for _, test := range testdata {
+		if test.ignore {
+			continue
+		}
+		lit := strings.Replace(test.objlit, "\n", "\r\n", -1)
+		m, err := LoadMeshFromReader(iotest.OneByteReader(strings.NewReader(lit)))
+		if err != nil {
+			t.Fatalf("%v: unable to load mesh: %v", test.title, err)
+		}
+		if len(m.Faces) != len(test.mesh.Faces) {
+			t.Fatalf("%v: expecting %v faces but got %v", test.title, len(test.mesh.Faces), len(m.Faces))
+		}
+		for i, _ := range m.Faces {
+			if !m.Faces[i].Same(&test.mesh.Faces[i]) {
+				t.Fatalf("%v: faces are different. Expecting %v got %v", test.title, test.mesh.Faces[i], m.Faces[i])
+			}
+		}
+	}
+}
+
+func TestMeshLoaderParseError(t *testing.T) {
+	_, err := LoadMeshFromReader(strings.NewReader("v 1.0 1.0 1.0\nv 1.0 abc\n"))
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Expecting a ParseError but got %v", err)
+	}
+	if perr.Pos != (Position{2, 7}) {
+		t.Errorf("Expecting the error at 2:7 but got %v", err)
+	}
+}
+
+func TestMeshLoaderChanParseError(t *testing.T) {
+	p := NewLiteralParser("v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3 x\nf 1 2 3\n")
+	go p.Parse()
+	_, err := LoadMesh(p.Tokens)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expecting io.ErrUnexpectedEOF but got %v", err)
+	}
+}
+
+func TestMeshLoaderByteOrderMark(t *testing.T) {
+	for _, lit := range []string{
+		"\ufeffv 0 0 0\nv 1 0 0\nv 0 1 0\nv 1 1 0\nf 1 2 3\n",
+		"\ufeff# Blender\nv 0 0 0\nv 1 0 0\nv 0 1 0\nv 1 1 0\nf 1 2 3\n",
+	} {
+		for _, opts := range []LoadOptions{{Strict: true}, {Strict: true, Workers: 2}} {
+			m, err := opts.LoadMesh(strings.NewReader(lit))
+			if err != nil {
+				t.Fatalf("Unable to load mesh: %v", err)
+			}
+			if len(m.Faces) != 1 || !m.Faces[0].Vertices.Same(VertexList{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}) {
+				t.Errorf("Invalid faces %v", m.Faces)
+			}
+		}
+	}
+
+	p := NewLiteralParser("\ufeffv 1 2 3\n")
+	if tok, _ := p.NextToken(); tok.Kind != VertexDecl || tok.Pos != (Position{1, 1}) {
+		t.Errorf("Expecting a vertex at 1:1 but got %v at %v", &tok, &tok.Pos)
+	}
+}
+
+func TestMeshLoaderInvalidUTF8(t *testing.T) {
+	// comments aren't decoded
+	lit := "# Cr\xe9\xe9 par\nv 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3\n"
+	for _, opts := range []LoadOptions{{}, {Strict: true}, {Strict: true, Workers: 2}} {
+		if _, err := opts.LoadMesh(strings.NewReader(lit)); err != nil {
+			t.Errorf("Unexpected error %v", err)
 		}
 	}
+
+	lit = "v 0 0 0\nv 1 0 0\nv 0 1 0\nusemtl caf\xe9\nf 1 2 3\n"
+	var warnings []Warning
+	opts := LoadOptions{OnWarning: func(w Warning) { warnings = append(warnings, w) }}
+	m, err := opts.LoadMesh(strings.NewReader(lit))
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	if materialName(m.Faces[0].Material) != "caf\xe9" {
+		t.Errorf("Expecting the name as is got %q", materialName(m.Faces[0].Material))
+	}
+	if len(warnings) != 1 || warnings[0].Pos != (Position{4, 8}) {
+		t.Errorf("Expecting a warning at 4:8 got %v", warnings)
+	}
+
+	strict := LoadOptions{Strict: true}
+	_, err = strict.LoadMesh(strings.NewReader(lit))
+	var lerr *MeshLoadError
+	if !errors.As(err, &lerr) || !errors.Is(err, ErrInvalidUTF8) || lerr.Pos != (Position{4, 8}) {
+		t.Errorf("Expecting ErrInvalidUTF8 at 4:8 but got %v", err)
+	}
+}
+
+func TestMeshLoadError(t *testing.T) {
+	_, err := LoadMeshFromFile("testdata/errors/out-of-range.obj")
+	var lerr *MeshLoadError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("Expecting a MeshLoadError but got %v", err)
+	}
+	if !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("Expecting ErrIndexOutOfRange but got %v", err)
+	}
+	expected := "testdata/errors/out-of-range.obj:5:7: face index 4 out of range (3 vertices)"
+	if err.Error() != expected {
+		t.Errorf("Expecting %q but got %q", expected, err.Error())
+	}
+	if lerr.Token == nil || lerr.Token.Kind != NumberLit || lerr.Token.Val != "4" {
+		t.Errorf("Invalid offending token %v", lerr.Token)
+	}
 }
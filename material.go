@@ -0,0 +1,421 @@
+package wfobj
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Represent a RGB color
+type Color struct {
+	R, G, B float32
+}
+
+// Represent a texture map statement and its options
+type TextureMap struct {
+	File string
+	// -blendu and -blendv
+	BlendU, BlendV bool
+	// -cc
+	ColorCorrection bool
+	// -clamp
+	Clamp bool
+	// -bm
+	BumpMultiplier float32
+	// -boost
+	Boost float32
+	// -mm base gain
+	Base, Gain float32
+	// -o, -s and -t
+	Origin, Scale, Turbulence Vertex
+	// -texres
+	Resolution int
+	// -imfchan
+	Channel string
+	// -type, only used by refl
+	Type string
+}
+
+// Create a texture map with the default options
+func NewTextureMap(file string) *TextureMap {
+	return &TextureMap{
+		File:           file,
+		BlendU:         true,
+		BlendV:         true,
+		BumpMultiplier: 1,
+		Gain:           1,
+		Scale:          Vertex{1, 1, 1},
+	}
+}
+
+// Represent a material declared by a newmtl statement
+type Material struct {
+	Name string
+	// Ka
+	Ambient Color
+	// Kd
+	Diffuse Color
+	// Ks
+	Specular Color
+	// Ke
+	Emissive Color
+	// Tf
+	TransmissionFilter Color
+	// Ns
+	SpecularExponent float32
+	// d, Tr is stored as 1 - Tr
+	Dissolve float32
+	// Ni
+	OpticalDensity float32
+	// sharpness
+	Sharpness float32
+	// illum
+	Illum int
+
+	// map_Ka
+	AmbientMap *TextureMap
+	// map_Kd
+	DiffuseMap *TextureMap
+	// map_Ks
+	SpecularMap *TextureMap
+	// map_Ke
+	EmissiveMap *TextureMap
+	// map_Ns
+	SpecularExponentMap *TextureMap
+	// map_d
+	DissolveMap *TextureMap
+	// map_Bump or bump
+	BumpMap *TextureMap
+	// disp
+	DisplacementMap *TextureMap
+	// decal
+	DecalMap *TextureMap
+	// refl
+	ReflectionMap *TextureMap
+}
+
+// Create a material with the default values
+func NewMaterial(name string) *Material {
+	return &Material{Name: name, Dissolve: 1, OpticalDensity: 1, Sharpness: 60}
+}
+
+// An error that happened while loading a .mtl file
+type MaterialLoadError struct {
+	// file name, empty if not loading a file
+	File string
+	// line of the offending statement
+	Line int
+	Err  error
+}
+
+// Return the error for the line
+//
+// If val isn't an error it is converted to one
+func NewMaterialLoadError(file string, line int, val interface{}) *MaterialLoadError {
+	err := &MaterialLoadError{File: file, Line: line}
+	if cause, ok := val.(error); ok {
+		err.Err = cause
+	} else {
+		err.Err = errors.New(fmt.Sprintf("%v", val))
+	}
+	return err
+}
+
+func (e *MaterialLoadError) Error() string {
+	if len(e.File) == 0 {
+		return fmt.Sprintf("%v: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("%v:%v: %v", e.File, e.Line, e.Err)
+}
+
+// Return the underlying error
+func (e *MaterialLoadError) Unwrap() error {
+	return e.Err
+}
+
+type materialLoader struct {
+	// file name used in errors
+	file      string
+	materials []Material
+	current   *Material
+	fields    []string
+	line      int
+}
+
+// Return the field at idx or an empty string if there is none
+func (m *materialLoader) field(idx int) string {
+	if idx >= len(m.fields) {
+		return ""
+	}
+	return m.fields[idx]
+}
+
+// Parse the field at idx as a float number
+func (m *materialLoader) float(idx int) float32 {
+	if idx >= len(m.fields) {
+		panic(fmt.Sprintf("Expecting a number after %v", m.fields[0]))
+	}
+	num, err := strconv.ParseFloat(m.fields[idx], 32)
+	if err != nil {
+		panic(err)
+	}
+	return float32(num)
+}
+
+// Parse the field at idx as a integer number
+func (m *materialLoader) int(idx int) int {
+	if idx >= len(m.fields) {
+		panic(fmt.Sprintf("Expecting a number after %v", m.fields[0]))
+	}
+	num, err := strconv.Atoi(m.fields[idx])
+	if err != nil {
+		panic(err)
+	}
+	return num
+}
+
+// Parse the field at idx as on/off
+func (m *materialLoader) flag(idx int) bool {
+	if idx >= len(m.fields) {
+		panic(fmt.Sprintf("Expecting on or off after %v", m.fields[idx-1]))
+	}
+	switch m.fields[idx] {
+	case "on":
+		return true
+	case "off":
+		return false
+	}
+	panic(fmt.Sprintf("Expecting on or off but got %v", m.fields[idx]))
+}
+
+// Check if the field at idx is a number
+func (m *materialLoader) isNumber(idx int) bool {
+	if idx >= len(m.fields) {
+		return false
+	}
+	_, err := strconv.ParseFloat(m.fields[idx], 32)
+	return err == nil
+}
+
+// Read the r [g b] information of a color statement
+//
+// The xyz form is read as rgb, spectral curves are ignored
+func (m *materialLoader) readColor() (c Color) {
+	idx := 1
+	switch m.field(idx) {
+	case "spectral":
+		return
+	case "xyz":
+		idx++
+	}
+	c.R = m.float(idx)
+	c.G, c.B = c.R, c.R
+	if m.isNumber(idx + 1) {
+		c.G = m.float(idx + 1)
+		c.B = m.float(idx + 2)
+	}
+	return
+}
+
+// Read the u [v [w]] arguments of a texture option
+func (m *materialLoader) readOptionVertex(idx int, v *Vertex) int {
+	v.X = m.float(idx)
+	idx++
+	if m.isNumber(idx) {
+		v.Y = m.float(idx)
+		idx++
+	}
+	if m.isNumber(idx) {
+		v.Z = m.float(idx)
+		idx++
+	}
+	return idx
+}
+
+// Read the options and the file name of a texture map statement
+func (m *materialLoader) readTextureMap() *TextureMap {
+	t := NewTextureMap("")
+	idx := 1
+	for idx < len(m.fields) && strings.HasPrefix(m.fields[idx], "-") {
+		opt := m.fields[idx]
+		idx++
+		switch opt {
+		case "-blendu":
+			t.BlendU = m.flag(idx)
+			idx++
+		case "-blendv":
+			t.BlendV = m.flag(idx)
+			idx++
+		case "-cc":
+			t.ColorCorrection = m.flag(idx)
+			idx++
+		case "-clamp":
+			t.Clamp = m.flag(idx)
+			idx++
+		case "-bm":
+			t.BumpMultiplier = m.float(idx)
+			idx++
+		case "-boost":
+			t.Boost = m.float(idx)
+			idx++
+		case "-mm":
+			t.Base = m.float(idx)
+			t.Gain = m.float(idx + 1)
+			idx += 2
+		case "-o":
+			idx = m.readOptionVertex(idx, &t.Origin)
+		case "-s":
+			idx = m.readOptionVertex(idx, &t.Scale)
+		case "-t":
+			idx = m.readOptionVertex(idx, &t.Turbulence)
+		case "-texres":
+			t.Resolution = m.int(idx)
+			idx++
+		case "-imfchan":
+			t.Channel = m.field(idx)
+			idx++
+		case "-type":
+			t.Type = m.field(idx)
+			idx++
+		default:
+			panic(fmt.Sprintf("Unknown texture option %v", opt))
+		}
+	}
+	if idx >= len(m.fields) {
+		panic(fmt.Sprintf("Expecting a file name after %v", m.fields[0]))
+	}
+	t.File = strings.Join(m.fields[idx:], " ")
+	return t
+}
+
+// Return the material being declared or panic if there is none
+func (m *materialLoader) material() *Material {
+	if m.current == nil {
+		panic(fmt.Sprintf("Unexpected %v before newmtl", m.fields[0]))
+	}
+	return m.current
+}
+
+// Process the statement stored in fields
+func (m *materialLoader) readStatement() {
+	if m.fields[0] == "newmtl" {
+		m.flush()
+		m.current = NewMaterial(strings.Join(m.fields[1:], " "))
+		return
+	}
+
+	mat := m.material()
+	switch m.fields[0] {
+	case "Ka":
+		mat.Ambient = m.readColor()
+	case "Kd":
+		mat.Diffuse = m.readColor()
+	case "Ks":
+		mat.Specular = m.readColor()
+	case "Ke":
+		mat.Emissive = m.readColor()
+	case "Tf":
+		mat.TransmissionFilter = m.readColor()
+	case "Ns":
+		mat.SpecularExponent = m.float(1)
+	case "d":
+		idx := 1
+		if m.field(idx) == "-halo" {
+			idx++
+		}
+		mat.Dissolve = m.float(idx)
+	case "Tr":
+		mat.Dissolve = 1 - m.float(1)
+	case "Ni":
+		mat.OpticalDensity = m.float(1)
+	case "sharpness":
+		mat.Sharpness = m.float(1)
+	case "illum":
+		mat.Illum = m.int(1)
+	case "map_Ka":
+		mat.AmbientMap = m.readTextureMap()
+	case "map_Kd":
+		mat.DiffuseMap = m.readTextureMap()
+	case "map_Ks":
+		mat.SpecularMap = m.readTextureMap()
+	case "map_Ke":
+		mat.EmissiveMap = m.readTextureMap()
+	case "map_Ns":
+		mat.SpecularExponentMap = m.readTextureMap()
+	case "map_d":
+		mat.DissolveMap = m.readTextureMap()
+	case "map_Bump", "map_bump", "bump":
+		mat.BumpMap = m.readTextureMap()
+	case "disp":
+		mat.DisplacementMap = m.readTextureMap()
+	case "decal":
+		mat.DecalMap = m.readTextureMap()
+	case "refl":
+		mat.ReflectionMap = m.readTextureMap()
+	}
+	// unknown statements are ignored
+}
+
+// Append the material being declared to the list
+func (m *materialLoader) flush() {
+	if m.current != nil {
+		m.materials = append(m.materials, *m.current)
+		m.current = nil
+	}
+}
+
+func (m *materialLoader) Load(r io.Reader) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = NewMaterialLoadError(m.file, m.line, p)
+		}
+	}()
+
+	m.materials = make([]Material, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m.line++
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx != -1 {
+			line = line[:idx]
+		}
+		m.fields = strings.Fields(line)
+		if len(m.fields) == 0 {
+			continue
+		}
+		m.readStatement()
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	m.flush()
+	return
+}
+
+// Load the materials declared in a .mtl file
+func LoadMaterials(r io.Reader) (mats []Material, err error) {
+	return loadMaterials(r, "")
+}
+
+// Run the material loader, file is used in errors
+func loadMaterials(r io.Reader, file string) (mats []Material, err error) {
+	ml := &materialLoader{file: file}
+	err = ml.Load(r)
+	mats = ml.materials
+	return
+}
+
+// Load the materials from the given .mtl file
+func LoadMaterialsFromFile(file string) (mats []Material, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	mats, err = loadMaterials(f, file)
+	return
+}
@@ -0,0 +1,63 @@
+package wfobj
+
+import (
+	"strings"
+	"testing"
+)
+
+const mtllit = `# comment
+newmtl Brick
+Ka 0.2 0.1 0.1
+Kd 0.8 0.4 0.3
+Ks 0.5
+Ns 32.0
+Tr 0.25
+illum 2
+map_Kd -s 2 2 1 -o 0.5 0 0 -clamp on brick.png
+map_Bump -bm 0.3 brick bump.png
+
+newmtl Glass
+d 0.1
+Ni 1.5
+`
+
+func TestMaterialLoader(t *testing.T) {
+	mats, err := LoadMaterials(strings.NewReader(mtllit))
+	if err != nil {
+		t.Fatalf("Unable to load materials: %v", err)
+	}
+	if len(mats) != 2 {
+		t.Fatalf("Expecting 2 materials but got %v", len(mats))
+	}
+
+	brick := &mats[0]
+	if brick.Name != "Brick" {
+		t.Errorf("Expecting Brick but got %v", brick.Name)
+	}
+	if brick.Diffuse != (Color{0.8, 0.4, 0.3}) {
+		t.Errorf("Invalid diffuse color %v", brick.Diffuse)
+	}
+	if brick.Specular != (Color{0.5, 0.5, 0.5}) {
+		t.Errorf("Invalid specular color %v", brick.Specular)
+	}
+	if brick.SpecularExponent != 32 || brick.Illum != 2 || brick.Dissolve != 0.75 {
+		t.Errorf("Invalid Ns/illum/d %v/%v/%v", brick.SpecularExponent, brick.Illum, brick.Dissolve)
+	}
+	if brick.DiffuseMap == nil {
+		t.Fatalf("Expecting a diffuse map")
+	}
+	if brick.DiffuseMap.File != "brick.png" || !brick.DiffuseMap.Clamp {
+		t.Errorf("Invalid diffuse map %v", brick.DiffuseMap)
+	}
+	if !brick.DiffuseMap.Scale.Same(Vertex{2, 2, 1}) || !brick.DiffuseMap.Origin.Same(Vertex{0.5, 0, 0}) {
+		t.Errorf("Invalid diffuse map scale/origin %v", brick.DiffuseMap)
+	}
+	if brick.BumpMap == nil || brick.BumpMap.File != "brick bump.png" || brick.BumpMap.BumpMultiplier != 0.3 {
+		t.Errorf("Invalid bump map %v", brick.BumpMap)
+	}
+
+	glass := &mats[1]
+	if glass.Dissolve != 0.1 || glass.OpticalDensity != 1.5 {
+		t.Errorf("Invalid d/Ni %v/%v", glass.Dissolve, glass.OpticalDensity)
+	}
+}
@@ -0,0 +1,229 @@
+package wfobj
+
+import (
+	"math"
+)
+
+// A 4x4 matrix stored by rows
+//
+// Vertices are handled as column vectors, so a point p
+// is transformed by m as m * p and m.Mul(n) applies n first
+type Matrix4 [4][4]float32
+
+// Return the identity matrix
+func Identity() Matrix4 {
+	return Matrix4{
+		{1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1},
+	}
+}
+
+// Return a matrix that moves points by v
+func Translate(v Vertex) Matrix4 {
+	m := Identity()
+	m[0][3], m[1][3], m[2][3] = v.X, v.Y, v.Z
+	return m
+}
+
+// Return a matrix that scales each axis by the components of v
+func Scale(v Vertex) Matrix4 {
+	m := Identity()
+	m[0][0], m[1][1], m[2][2] = v.X, v.Y, v.Z
+	return m
+}
+
+// Return a matrix that rotates angle radians around the x axis
+func RotateX(angle float32) Matrix4 {
+	s, c := sincos(angle)
+	m := Identity()
+	m[1][1], m[1][2] = c, -s
+	m[2][1], m[2][2] = s, c
+	return m
+}
+
+// Return a matrix that rotates angle radians around the y axis
+func RotateY(angle float32) Matrix4 {
+	s, c := sincos(angle)
+	m := Identity()
+	m[0][0], m[0][2] = c, s
+	m[2][0], m[2][2] = -s, c
+	return m
+}
+
+// Return a matrix that rotates angle radians around the z axis
+func RotateZ(angle float32) Matrix4 {
+	s, c := sincos(angle)
+	m := Identity()
+	m[0][0], m[0][1] = c, -s
+	m[1][0], m[1][1] = s, c
+	return m
+}
+
+// Return a matrix that rotates angle radians around the axis
+//
+// The rotation is counter-clockwise when looking from the
+// tip of the axis to the origin
+func Rotate(angle float32, axis Vertex) Matrix4 {
+	a := axis.Normalize()
+	s, c := sincos(angle)
+	t := 1 - c
+	return Matrix4{
+		{t*a.X*a.X + c, t*a.X*a.Y - s*a.Z, t*a.X*a.Z + s*a.Y, 0},
+		{t*a.X*a.Y + s*a.Z, t*a.Y*a.Y + c, t*a.Y*a.Z - s*a.X, 0},
+		{t*a.X*a.Z - s*a.Y, t*a.Y*a.Z + s*a.X, t*a.Z*a.Z + c, 0},
+		{0, 0, 0, 1},
+	}
+}
+
+func sincos(angle float32) (float32, float32) {
+	s, c := math.Sincos(float64(angle))
+	return float32(s), float32(c)
+}
+
+// Return m * n, the transform that applies n and then m
+func (m Matrix4) Mul(n Matrix4) (r Matrix4) {
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			for k := 0; k < 4; k++ {
+				r[i][j] += m[i][k] * n[k][j]
+			}
+		}
+	}
+	return
+}
+
+// Return the matrix with rows and columns swapped
+func (m Matrix4) Transpose() (r Matrix4) {
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			r[i][j] = m[j][i]
+		}
+	}
+	return
+}
+
+// Return the inverse of the matrix
+//
+// ok is false if the matrix is singular
+func (m Matrix4) Inverse() (inv Matrix4, ok bool) {
+	// Gauss-Jordan elimination with partial pivoting
+	var a [4][8]float64
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			a[i][j] = float64(m[i][j])
+		}
+		a[i][i+4] = 1
+	}
+	for col := 0; col < 4; col++ {
+		pivot := col
+		for row := col + 1; row < 4; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		div := a[col][col]
+		for j, _ := range a[col] {
+			a[col][j] /= div
+		}
+		for row := 0; row < 4; row++ {
+			if row == col {
+				continue
+			}
+			f := a[row][col]
+			for j, _ := range a[row] {
+				a[row][j] -= f * a[col][j]
+			}
+		}
+	}
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			inv[i][j] = float32(a[i][j+4])
+		}
+	}
+	return inv, true
+}
+
+// Return the point v transformed by the matrix, divided by w
+func (m Matrix4) TransformPoint(v Vertex) Vertex {
+	r := Vertex{
+		m[0][0]*v.X + m[0][1]*v.Y + m[0][2]*v.Z + m[0][3],
+		m[1][0]*v.X + m[1][1]*v.Y + m[1][2]*v.Z + m[1][3],
+		m[2][0]*v.X + m[2][1]*v.Y + m[2][2]*v.Z + m[2][3],
+	}
+	w := m[3][0]*v.X + m[3][1]*v.Y + m[3][2]*v.Z + m[3][3]
+	if w != 1 && w != 0 {
+		r = r.Scale(1 / w)
+	}
+	return r
+}
+
+// Return the direction v transformed by the matrix, ignoring the translation
+func (m Matrix4) TransformVector(v Vertex) Vertex {
+	return Vertex{
+		m[0][0]*v.X + m[0][1]*v.Y + m[0][2]*v.Z,
+		m[1][0]*v.X + m[1][1]*v.Y + m[1][2]*v.Z,
+		m[2][0]*v.X + m[2][1]*v.Y + m[2][2]*v.Z,
+	}
+}
+
+// Transform the vertices of the faces, points and lines of the mesh by mat
+//
+// Normals are transformed by the inverse-transpose of m and normalized,
+// so they stay perpendicular to the faces under non-uniform scales.
+// If m is singular the normals are transformed by m itself.
+// Texture coordinates aren't changed
+func (m *Mesh) Transform(mat Matrix4) {
+	normalMat := mat
+	if inv, ok := mat.Inverse(); ok {
+		normalMat = inv.Transpose()
+	}
+	for i, _ := range m.Faces {
+		f := &m.Faces[i]
+		for j, v := range f.Vertices {
+			f.Vertices[j] = mat.TransformPoint(v)
+		}
+		for j, n := range f.Normals {
+			f.Normals[j] = normalMat.TransformVector(n).Normalize()
+		}
+	}
+	for i, v := range m.Points {
+		m.Points[i] = mat.TransformPoint(v)
+	}
+	for i, _ := range m.Lines {
+		l := &m.Lines[i]
+		for j, v := range l.Vertices {
+			l.Vertices[j] = mat.TransformPoint(v)
+		}
+	}
+}
+
+// Move the mesh so the center of its bounding box is at the origin
+func (m *Mesh) CenterAtOrigin() {
+	b := m.Bounds()
+	if b.Empty {
+		return
+	}
+	m.Transform(Translate(b.Center.Scale(-1)))
+}
+
+// Center the mesh at the origin and scale it uniformly so it
+// fits a cube of size one, keeping its proportions
+func (m *Mesh) Normalize() {
+	b := m.Bounds()
+	if b.Empty {
+		return
+	}
+	size := b.Size()
+	extent := max32(size.X, max32(size.Y, size.Z))
+	mat := Translate(b.Center.Scale(-1))
+	if extent > 0 {
+		mat = Scale(Vertex{1 / extent, 1 / extent, 1 / extent}).Mul(mat)
+	}
+	m.Transform(mat)
+}
@@ -0,0 +1,85 @@
+package wfobj
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMatrix4(t *testing.T) {
+	p := Vertex{1, 2, 3}
+	if r := Translate(Vertex{1, 1, 1}).TransformPoint(p); !r.ApproxEqual(Vertex{2, 3, 4}, 1e-5) {
+		t.Errorf("Invalid translation %v", r)
+	}
+	if r := Translate(Vertex{1, 1, 1}).TransformVector(p); !r.ApproxEqual(p, 1e-5) {
+		t.Errorf("Vectors must not be translated %v", r)
+	}
+	if r := RotateZ(math.Pi / 2).TransformPoint(Vertex{1, 0, 0}); !r.ApproxEqual(Vertex{0, 1, 0}, 1e-5) {
+		t.Errorf("Invalid z rotation %v", r)
+	}
+	if r := RotateX(math.Pi / 2).TransformPoint(Vertex{0, 1, 0}); !r.ApproxEqual(Vertex{0, 0, 1}, 1e-5) {
+		t.Errorf("Invalid x rotation %v", r)
+	}
+	if r := RotateY(math.Pi / 2).TransformPoint(Vertex{0, 0, 1}); !r.ApproxEqual(Vertex{1, 0, 0}, 1e-5) {
+		t.Errorf("Invalid y rotation %v", r)
+	}
+	if r := Rotate(1, Vertex{0, 0, 2}); !near4(r, RotateZ(1)) {
+		t.Errorf("Rotate around z must match RotateZ %v", r)
+	}
+
+	m := Translate(Vertex{1, 2, 3}).Mul(RotateY(0.5)).Mul(Scale(Vertex{2, 3, 4}))
+	inv, ok := m.Inverse()
+	if !ok {
+		t.Fatalf("Matrix must be invertible")
+	}
+	if !near4(m.Mul(inv), Identity()) {
+		t.Errorf("Invalid inverse %v", inv)
+	}
+	if _, ok := Scale(Vertex{1, 0, 1}).Inverse(); ok {
+		t.Errorf("Singular matrix must not be invertible")
+	}
+}
+
+func near4(a, b Matrix4) bool {
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			if math.Abs(float64(a[i][j]-b[i][j])) > 1e-5 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestMeshTransform(t *testing.T) {
+	m := &Mesh{Faces: []Face{{
+		Vertices: VertexList{{0, 0, 0}, {1, 0, 0}, {0, 1, 1}},
+		Normals:  VertexList{{0, -1, 1}, {0, -1, 1}, {0, -1, 1}},
+	}}}
+	m.Transform(Scale(Vertex{1, 2, 1}))
+	f := &m.Faces[0]
+	if !f.Vertices[2].ApproxEqual(Vertex{0, 2, 1}, 1e-5) {
+		t.Errorf("Invalid vertex %v", f.Vertices[2])
+	}
+	n := faceNormal(f)
+	if !f.Normals[0].ApproxEqual(n, 1e-5) {
+		t.Errorf("Normal %v must stay perpendicular to the face %v", f.Normals[0], n)
+	}
+}
+
+func TestMeshNormalize(t *testing.T) {
+	m, err := LoadMeshFromFile("cube.obj")
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	m.Transform(Translate(Vertex{5, 5, 5}).Mul(Scale(Vertex{3, 1, 1})))
+	m.CenterAtOrigin()
+	b := m.Bounds()
+	if !b.Center.ApproxEqual(Vertex{}, 1e-5) {
+		t.Errorf("Mesh must be centered %v", b.Center)
+	}
+	m.Normalize()
+	b = m.Bounds()
+	if !b.Min.ApproxEqual(Vertex{-0.5, -1.0 / 6, -1.0 / 6}, 1e-5) || !b.Max.ApproxEqual(Vertex{0.5, 1.0 / 6, 1.0 / 6}, 1e-5) {
+		t.Errorf("Invalid bounds after normalize %v %v", b.Min, b.Max)
+	}
+}
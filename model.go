@@ -1,25 +1,86 @@
 package wfobj
 
+import (
+	"math"
+)
+
 // Represent a 3D vertex
 type Vertex struct {
 	X, Y, Z float32
 }
 
 // Check if two vertexes are the same
-func (v *Vertex) Same(other *Vertex) bool {
+func (v Vertex) Same(other Vertex) bool {
 	return v.X == other.X && v.Y == other.Y && v.Z == other.Z
 }
 
-// Subtract one for another
-func (v *Vertex) Sub(other *Vertex) (ret *Vertex) {
-	ret = &Vertex{other.X - v.X, other.Y - v.Y, other.Z - v.Z}
-	return
+// Check if two vertexes are the same within eps in each axis
+func (v Vertex) ApproxEqual(other Vertex, eps float32) bool {
+	d := v.Sub(other)
+	return abs32(d.X) <= eps && abs32(d.Y) <= eps && abs32(d.Z) <= eps
+}
+
+// Return v - other
+func (v Vertex) Sub(other Vertex) Vertex {
+	return Vertex{v.X - other.X, v.Y - other.Y, v.Z - other.Z}
+}
+
+// Return v + other
+func (v Vertex) Add(other Vertex) Vertex {
+	return Vertex{v.X + other.X, v.Y + other.Y, v.Z + other.Z}
+}
+
+// Multiply each axis by s
+func (v Vertex) Scale(s float32) Vertex {
+	return Vertex{v.X * s, v.Y * s, v.Z * s}
+}
+
+// Dot product of this and another
+func (v Vertex) Dot(other Vertex) float32 {
+	return v.X*other.X + v.Y*other.Y + v.Z*other.Z
+}
+
+// Cross product of this and another
+func (v Vertex) Cross(other Vertex) Vertex {
+	return Vertex{
+		v.Y*other.Z - v.Z*other.Y,
+		v.Z*other.X - v.X*other.Z,
+		v.X*other.Y - v.Y*other.X,
+	}
+}
+
+// Length of the vector
+func (v Vertex) Length() float32 {
+	return float32(math.Sqrt(float64(v.Dot(v))))
+}
+
+// Return a vector with the same direction and length one
+//
+// The zero vector is returned as is
+func (v Vertex) Normalize() Vertex {
+	l := v.Length()
+	if l == 0 {
+		return v
+	}
+	return Vertex{v.X / l, v.Y / l, v.Z / l}
 }
 
-// Add on vector to another
-func (v *Vertex) Add(other *Vertex) (ret *Vertex) {
-	ret = &Vertex{other.X + v.X, other.Y + v.Y, other.Z + v.Z}
-	return
+// Linear interpolation, t = 0 returns v and t = 1 returns other
+func (v Vertex) Lerp(other Vertex, t float32) Vertex {
+	return v.Add(other.Sub(v).Scale(t))
+}
+
+// Distance between two points
+func (v Vertex) Distance(other Vertex) float32 {
+	return v.Sub(other).Length()
+}
+
+// Absolute value of a float32
+func abs32(f float32) float32 {
+	if f < 0 {
+		return -f
+	}
+	return f
 }
 
 // Represent a vertex list
@@ -30,7 +91,7 @@ func (v VertexList) Same(other VertexList) bool {
 		return false
 	}
 	for i, _ := range other {
-		if !v[i].Same(&other[i]) {
+		if !v[i].Same(other[i]) {
 			return false
 		}
 	}
@@ -39,9 +100,24 @@ func (v VertexList) Same(other VertexList) bool {
 
 // Represent one face of the object
 // Vertices must be in the right draw order
+//
+// Normals and TexCoords are either empty or have one entry
+// for each vertex. Texture coordinates store u, v and w in X, Y and Z
+//
+// Weights and Colors are the optional w and r g b components of the
+// v statements, either empty or with one entry for each vertex
+//
+// Material is the one active when the face was declared, nil if none,
+// and SmoothingGroup is the group number of the last s statement,
+// zero if smoothing is off
 type Face struct {
-	Vertices VertexList
-	Normals  VertexList
+	Vertices       VertexList
+	Normals        VertexList
+	TexCoords      VertexList
+	Weights        []float32
+	Colors         []Color
+	Material       *Material
+	SmoothingGroup int
 }
 
 // Check if two faces are equal
@@ -50,8 +126,303 @@ func (f *Face) Same(other *Face) bool {
 	return f.Vertices.Same(other.Vertices)
 }
 
+// Represent a polyline declared by a l statement
+//
+// TexCoords, Weights and Colors are either empty or have one
+// entry for each vertex, see Face
+type Line struct {
+	Vertices  VertexList
+	TexCoords VertexList
+	Weights   []float32
+	Colors    []Color
+	// material active when the line was declared, nil if none
+	Material *Material
+}
+
 // Represent a mesh made by a collection of faces/material
-// TODO Implement material
 type Mesh struct {
 	Faces []Face
+	// Vertices referenced by the p statements
+	Points VertexList
+	// w and r g b of the points, either empty or
+	// with one entry for each point
+	PointWeights []float32
+	PointColors  []Color
+	// Polylines declared by the l statements
+	Lines []Line
+	// Files referenced by the mtllib statements
+	MaterialLibs []string
+	// Materials referenced by the faces, indexed by name
+	Materials map[string]*Material
+	// Curves and surfaces
+	FreeForm FreeFormGeometry
+}
+
+// Return the material with the given name
+//
+// If the material isn't known yet, a material with default values
+// is created and will be filled when its library is bound
+func (m *Mesh) Material(name string) *Material {
+	if m.Materials == nil {
+		m.Materials = make(map[string]*Material)
+	}
+	return findMaterial(m.Materials, name)
+}
+
+// Bind the materials to the mesh
+//
+// Faces that reference a material by name will point to the new values
+func (m *Mesh) BindMaterials(mats []Material) {
+	if m.Materials == nil {
+		m.Materials = make(map[string]*Material)
+	}
+	bindMaterials(m.Materials, mats)
+}
+
+// Index of the vertex, texture coordinate and normal of a face corner
+//
+// Indices are zero based, -1 means the information is absent
+type Index struct {
+	Vertex, TexCoord, Normal int
+}
+
+// Represent one face of an indexed mesh
+type IndexedFace struct {
+	Indices        []Index
+	Material       *Material
+	SmoothingGroup int
+}
+
+// Represent one polyline of an indexed mesh
+//
+// The Normal of each index is always -1
+type IndexedLine struct {
+	Indices  []Index
+	Material *Material
+}
+
+// Represent a mesh where faces reference shared pools of
+// vertices, normals and texture coordinates
+//
+// The pools and the indices can be uploaded directly to vertex/index buffers
+type IndexedMesh struct {
+	Vertices  VertexList
+	Normals   VertexList
+	TexCoords VertexList
+	// w of each vertex, empty if no v statement has it
+	Weights []float32
+	// r g b of each vertex, empty if no v statement has it
+	Colors []Color
+	Faces  []IndexedFace
+	// indices of the vertices referenced by the p statements
+	Points []int
+	Lines  []IndexedLine
+	// Files referenced by the mtllib statements
+	MaterialLibs []string
+	// Materials referenced by the faces, indexed by name
+	Materials map[string]*Material
+	// Curves and surfaces
+	FreeForm FreeFormGeometry
+}
+
+// Return the material with the given name
+//
+// See Mesh.Material
+func (m *IndexedMesh) Material(name string) *Material {
+	if m.Materials == nil {
+		m.Materials = make(map[string]*Material)
+	}
+	return findMaterial(m.Materials, name)
+}
+
+// Bind the materials to the mesh
+//
+// See Mesh.BindMaterials
+func (m *IndexedMesh) BindMaterials(mats []Material) {
+	if m.Materials == nil {
+		m.Materials = make(map[string]*Material)
+	}
+	bindMaterials(m.Materials, mats)
+}
+
+// Expand the indexed mesh into a mesh where each face, point and
+// line holds a copy of its vertices, normals and texture coordinates
+//
+// Materials and free-form geometry are shared between both meshes
+func (m *IndexedMesh) Mesh() *Mesh {
+	mesh := &Mesh{}
+	mesh.Faces = make([]Face, len(m.Faces))
+	mesh.MaterialLibs = m.MaterialLibs
+	mesh.Materials = m.Materials
+	mesh.FreeForm = m.FreeForm
+	mesh.Points = make(VertexList, len(m.Points))
+	if len(m.Weights) > 0 {
+		mesh.PointWeights = make([]float32, len(m.Points))
+	}
+	if len(m.Colors) > 0 {
+		mesh.PointColors = make([]Color, len(m.Points))
+	}
+	for i, idx := range m.Points {
+		mesh.Points[i] = m.Vertices[idx]
+		if len(m.Weights) > 0 {
+			mesh.PointWeights[i] = m.Weights[idx]
+		}
+		if len(m.Colors) > 0 {
+			mesh.PointColors[i] = m.Colors[idx]
+		}
+	}
+	mesh.Lines = make([]Line, len(m.Lines))
+	for i, _ := range m.Lines {
+		src := &m.Lines[i]
+		l := &mesh.Lines[i]
+		l.Vertices = make(VertexList, 0, len(src.Indices))
+		l.TexCoords = make(VertexList, 0)
+		l.Material = src.Material
+		if len(m.Weights) > 0 {
+			l.Weights = make([]float32, 0, len(src.Indices))
+		}
+		if len(m.Colors) > 0 {
+			l.Colors = make([]Color, 0, len(src.Indices))
+		}
+		for _, idx := range src.Indices {
+			l.Vertices = append(l.Vertices, m.Vertices[idx.Vertex])
+			if len(m.Weights) > 0 {
+				l.Weights = append(l.Weights, m.Weights[idx.Vertex])
+			}
+			if len(m.Colors) > 0 {
+				l.Colors = append(l.Colors, m.Colors[idx.Vertex])
+			}
+			if idx.TexCoord >= 0 {
+				l.TexCoords = append(l.TexCoords, m.TexCoords[idx.TexCoord])
+			}
+		}
+	}
+	for i, _ := range m.Faces {
+		src := &m.Faces[i]
+		f := &mesh.Faces[i]
+		f.Vertices = make(VertexList, 0, len(src.Indices))
+		f.Normals = make(VertexList, 0)
+		f.TexCoords = make(VertexList, 0)
+		f.Material = src.Material
+		f.SmoothingGroup = src.SmoothingGroup
+		if len(m.Weights) > 0 {
+			f.Weights = make([]float32, 0, len(src.Indices))
+		}
+		if len(m.Colors) > 0 {
+			f.Colors = make([]Color, 0, len(src.Indices))
+		}
+		for _, idx := range src.Indices {
+			f.Vertices = append(f.Vertices, m.Vertices[idx.Vertex])
+			if len(m.Weights) > 0 {
+				f.Weights = append(f.Weights, m.Weights[idx.Vertex])
+			}
+			if len(m.Colors) > 0 {
+				f.Colors = append(f.Colors, m.Colors[idx.Vertex])
+			}
+			if idx.TexCoord >= 0 {
+				f.TexCoords = append(f.TexCoords, m.TexCoords[idx.TexCoord])
+			}
+			if idx.Normal >= 0 {
+				f.Normals = append(f.Normals, m.Normals[idx.Normal])
+			}
+		}
+	}
+	return mesh
+}
+
+// Return the material with the given name from mats,
+// creating one with default values if needed
+func findMaterial(mats map[string]*Material, name string) *Material {
+	mat, ok := mats[name]
+	if !ok {
+		mat = NewMaterial(name)
+		mats[name] = mat
+	}
+	return mat
+}
+
+// Copy the values of mats to the materials with the same name
+func bindMaterials(dst map[string]*Material, mats []Material) {
+	for i, _ := range mats {
+		*findMaterial(dst, mats[i].Name) = mats[i]
+	}
+}
+
+// A range of faces [Start, End) inside Mesh.Faces
+type FaceRange struct {
+	Start, End int
+}
+
+// Append the face to the last range or start a new one
+func appendFace(ranges []FaceRange, face int) []FaceRange {
+	if last := len(ranges) - 1; last >= 0 && ranges[last].End == face {
+		ranges[last].End++
+		return ranges
+	}
+	return append(ranges, FaceRange{face, face + 1})
+}
+
+// Represent a group declared by a g statement
+//
+// A face can belong to more than one group
+type Group struct {
+	Name   string
+	Ranges []FaceRange
+}
+
+// Represent an object declared by a o statement
+//
+// Faces declared before any o statement belong to an object without name
+type Object struct {
+	Name   string
+	Ranges []FaceRange
+	Groups []*Group
+}
+
+// Return the group with the given name or nil if there is none
+func (o *Object) Group(name string) *Group {
+	for _, g := range o.Groups {
+		if g.Name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+// Represent a mesh split in named objects and groups
+type Model struct {
+	Mesh    *Mesh
+	Objects []*Object
+}
+
+// Return the object with the given name or nil if there is none
+func (m *Model) Object(name string) *Object {
+	for _, o := range m.Objects {
+		if o.Name == name {
+			return o
+		}
+	}
+	return nil
+}
+
+// Return a new mesh with a copy of the faces in the ranges
+//
+// Materials are shared with the model
+func (m *Model) SubMesh(ranges []FaceRange) *Mesh {
+	mesh := &Mesh{}
+	mesh.Faces = make([]Face, 0)
+	mesh.MaterialLibs = m.Mesh.MaterialLibs
+	mesh.Materials = m.Mesh.Materials
+	for _, r := range ranges {
+		for i := r.Start; i < r.End; i++ {
+			f := m.Mesh.Faces[i]
+			f.Vertices = append(VertexList(nil), f.Vertices...)
+			f.Normals = append(VertexList(nil), f.Normals...)
+			f.TexCoords = append(VertexList(nil), f.TexCoords...)
+			f.Weights = append([]float32(nil), f.Weights...)
+			f.Colors = append([]Color(nil), f.Colors...)
+			mesh.Faces = append(mesh.Faces, f)
+		}
+	}
+	return mesh
 }
@@ -0,0 +1,44 @@
+package wfobj
+
+import (
+	"testing"
+)
+
+func TestVertexMath(t *testing.T) {
+	a := Vertex{1, 2, 3}
+	b := Vertex{4, 6, 3}
+
+	if s := b.Sub(a); !s.Same(Vertex{3, 4, 0}) {
+		t.Errorf("Sub must return v - other. Expecting %v got %v", Vertex{3, 4, 0}, s)
+	}
+	if s := a.Add(b); !s.Same(Vertex{5, 8, 6}) {
+		t.Errorf("Invalid Add %v", s)
+	}
+	if s := a.Scale(2); !s.Same(Vertex{2, 4, 6}) {
+		t.Errorf("Invalid Scale %v", s)
+	}
+	if d := a.Dot(b); d != 25 {
+		t.Errorf("Invalid Dot %v", d)
+	}
+	if c := (Vertex{1, 0, 0}).Cross(Vertex{0, 1, 0}); !c.Same(Vertex{0, 0, 1}) {
+		t.Errorf("Invalid Cross %v", c)
+	}
+	if l := b.Sub(a).Length(); l != 5 {
+		t.Errorf("Invalid Length %v", l)
+	}
+	if d := a.Distance(b); d != 5 {
+		t.Errorf("Invalid Distance %v", d)
+	}
+	if n := b.Sub(a).Normalize(); !n.ApproxEqual(Vertex{0.6, 0.8, 0}, 1e-6) {
+		t.Errorf("Invalid Normalize %v", n)
+	}
+	if n := (Vertex{}).Normalize(); !n.Same(Vertex{}) {
+		t.Errorf("The zero vector must be normalized to itself %v", n)
+	}
+	if l := a.Lerp(b, 0.5); !l.Same(Vertex{2.5, 4, 3}) {
+		t.Errorf("Invalid Lerp %v", l)
+	}
+	if !a.ApproxEqual(Vertex{1.05, 2, 3}, 0.1) || a.ApproxEqual(Vertex{1.2, 2, 3}, 0.1) {
+		t.Errorf("Invalid ApproxEqual")
+	}
+}
@@ -0,0 +1,107 @@
+package wfobj
+
+import (
+	"math"
+)
+
+// Return the unit normal of the face
+//
+// The normal is the sum of the normals of the triangles of a fan
+// around the first vertex, so concave polygons are handled too
+func faceNormal(f *Face) Vertex {
+	sum := Vertex{}
+	first := f.Vertices[0]
+	for i := 1; i+1 < len(f.Vertices); i++ {
+		e1 := f.Vertices[i].Sub(first)
+		e2 := f.Vertices[i+1].Sub(first)
+		sum = sum.Add(e1.Cross(e2))
+	}
+	return sum.Normalize()
+}
+
+// Return the angle of the face at the given corner
+func cornerAngle(f *Face, corner int) float64 {
+	n := len(f.Vertices)
+	cur := f.Vertices[corner]
+	prev := f.Vertices[(corner+n-1)%n].Sub(cur).Normalize()
+	next := f.Vertices[(corner+1)%n].Sub(cur).Normalize()
+	cos := float64(prev.Dot(next))
+	return math.Acos(math.Max(-1, math.Min(1, cos)))
+}
+
+// Set the normals of each face to the normal of its plane
+//
+// Faces with less than three vertices are ignored
+func (m *Mesh) ComputeFaceNormals() {
+	for i, _ := range m.Faces {
+		f := &m.Faces[i]
+		if len(f.Vertices) < 3 {
+			continue
+		}
+		n := faceNormal(f)
+		f.Normals = make(VertexList, len(f.Vertices))
+		for j, _ := range f.Normals {
+			f.Normals[j] = n
+		}
+	}
+}
+
+// A corner of a face
+type corner struct {
+	face, vertex int
+}
+
+// Set the normals of each face to the average of the normals of the faces
+// that share the same vertex position, weighted by the angle of each face
+// at that vertex
+//
+// Faces are only averaged if the angle between their normals is at most
+// angleThreshold radians. If any face has a smoothing group, faces are only
+// averaged with faces in the same group and faces with smoothing off are flat.
+// Faces with less than three vertices are ignored
+func (m *Mesh) ComputeVertexNormals(angleThreshold float32) {
+	normals := make([]Vertex, len(m.Faces))
+	corners := make(map[Vertex][]corner)
+	groups := false
+	for i, _ := range m.Faces {
+		f := &m.Faces[i]
+		if len(f.Vertices) < 3 {
+			continue
+		}
+		normals[i] = faceNormal(f)
+		for j, v := range f.Vertices {
+			corners[v] = append(corners[v], corner{i, j})
+		}
+		groups = groups || f.SmoothingGroup != 0
+	}
+
+	minCos := float32(math.Cos(float64(angleThreshold)))
+	for i, _ := range m.Faces {
+		f := &m.Faces[i]
+		if len(f.Vertices) < 3 {
+			continue
+		}
+		f.Normals = make(VertexList, len(f.Vertices))
+		for j, v := range f.Vertices {
+			sum := Vertex{}
+			for _, c := range corners[v] {
+				other := &m.Faces[c.face]
+				if c.face != i {
+					if groups && (f.SmoothingGroup == 0 || f.SmoothingGroup != other.SmoothingGroup) {
+						continue
+					}
+					if normals[i].Dot(normals[c.face]) < minCos {
+						continue
+					}
+				}
+				w := float32(cornerAngle(other, c.vertex))
+				sum = sum.Add(normals[c.face].Scale(w))
+			}
+			n := sum.Normalize()
+			if n.Same(Vertex{}) {
+				n = normals[i]
+			}
+			f.Normals[j] = n
+		}
+	}
+}
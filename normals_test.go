@@ -0,0 +1,84 @@
+package wfobj
+
+import (
+	"math"
+	"testing"
+)
+
+// Check if the vertices are equal within a small error
+func near(a, b Vertex) bool {
+	return a.ApproxEqual(b, 1e-5)
+}
+
+func TestComputeFaceNormals(t *testing.T) {
+	m, err := LoadMeshFromFile("cube.obj")
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	m.ComputeFaceNormals()
+	expected := []Vertex{{0, -1, 0}, {0, 1, 0}, {1, 0, 0}, {0, 0, 1}, {-1, 0, 0}, {0, 0, -1}}
+	for i, _ := range m.Faces {
+		f := &m.Faces[i]
+		if len(f.Normals) != len(f.Vertices) {
+			t.Fatalf("Expecting %v normals but got %v", len(f.Vertices), len(f.Normals))
+		}
+		for _, n := range f.Normals {
+			if !near(n, expected[i]) {
+				t.Errorf("Face %v: expecting normal %v but got %v", i, expected[i], n)
+			}
+		}
+	}
+}
+
+func TestComputeVertexNormals(t *testing.T) {
+	m, err := LoadMeshFromFile("cube.obj")
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+
+	// all the faces of the cube meet at 90 degrees
+	m.ComputeVertexNormals(math.Pi / 4)
+	flat := *m
+	flat.Faces = append([]Face(nil), m.Faces...)
+	flat.ComputeFaceNormals()
+	for i, _ := range m.Faces {
+		for j, n := range m.Faces[i].Normals {
+			if !near(n, flat.Faces[i].Normals[j]) {
+				t.Errorf("Face %v: expecting a flat normal %v but got %v", i, flat.Faces[i].Normals[j], n)
+			}
+		}
+	}
+
+	m.ComputeVertexNormals(math.Pi)
+	for i, _ := range m.Faces {
+		f := &m.Faces[i]
+		for j, v := range f.Vertices {
+			// each corner of the cube points away from the center
+			expected := v.Normalize()
+			if !near(f.Normals[j], expected) {
+				t.Errorf("Face %v: expecting normal %v at %v but got %v", i, expected, v, f.Normals[j])
+			}
+		}
+	}
+}
+
+func TestComputeVertexNormalsSmoothingGroups(t *testing.T) {
+	// two faces folded at 90 degrees along the x axis
+	m := &Mesh{
+		Faces: []Face{
+			Face{Vertices: VertexList{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}}, SmoothingGroup: 1},
+			Face{Vertices: VertexList{{0, 0, 0}, {0, 0, 1}, {1, 0, 1}, {1, 0, 0}}, SmoothingGroup: 2},
+		},
+	}
+	m.ComputeVertexNormals(math.Pi)
+	if !near(m.Faces[0].Normals[0], Vertex{0, 0, 1}) || !near(m.Faces[1].Normals[0], Vertex{0, 1, 0}) {
+		t.Errorf("Faces in different groups must not be smoothed %v %v", m.Faces[0].Normals, m.Faces[1].Normals)
+	}
+
+	m.Faces[1].SmoothingGroup = 1
+	m.ComputeVertexNormals(math.Pi)
+	expected := Vertex{0, 1, 1}.Normalize()
+	if !near(m.Faces[0].Normals[0], expected) || !near(m.Faces[1].Normals[0], expected) {
+		t.Errorf("Faces in the same group must be smoothed %v %v", m.Faces[0].Normals, m.Faces[1].Normals)
+	}
+}
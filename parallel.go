@@ -0,0 +1,208 @@
+package wfobj
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"runtime"
+)
+
+// Minimum size of the chunks lexed in parallel
+const chunkSize = 1 << 20
+
+// Tokens of a chunk of the input, followed by the error
+// that stopped the lexer, if any
+type chunkResult struct {
+	tokens []Token
+	err    error
+}
+
+// Token source that splits the input in chunks of whole lines, lexes
+// them in parallel and returns their tokens in the input order
+//
+// Indices are resolved by the loader that reads the tokens, so the
+// result is the same as parsing the input sequentially
+type chunkReader struct {
+	// file name used in errors
+	file string
+	// results of the chunks in the input order, at most
+	// one per worker is waiting to be read
+	pending chan chan chunkResult
+	// closed to stop reading the input
+	done chan struct{}
+	cur  chunkResult
+	head int
+	// Eof of the last chunk, returned after all the chunks
+	eof Token
+	err error
+}
+
+// Start lexing the contents of r using workers goroutines
+//
+// Close must be called to stop reading r if not all the tokens are read
+func newChunkReader(r io.Reader, file string, workers, size int, nonFinite NonFinitePolicy) *chunkReader {
+	c := &chunkReader{file, make(chan chan chunkResult, workers), make(chan struct{}), chunkResult{}, 0, Token{"", Eof, Position{1, 1}}, nil}
+	go c.split(bufio.NewReader(r), workers, size, nonFinite)
+	return c
+}
+
+// Read a chunk with at least size bytes ending at a line break
+// that isn't a line continuation
+func readChunk(r *bufio.Reader, size int) (chunk []byte, err error) {
+	chunk = make([]byte, size)
+	n, err := io.ReadFull(r, chunk)
+	chunk = chunk[:n]
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	for err == nil && !lineEnd(chunk) {
+		var c byte
+		if c, err = r.ReadByte(); err == nil {
+			chunk = append(chunk, c)
+		}
+	}
+	// don't split a \r\n line break
+	if err == nil && chunk[len(chunk)-1] == '\r' {
+		if next, _ := r.Peek(1); len(next) == 1 && next[0] == '\n' {
+			r.Discard(1)
+			chunk = append(chunk, '\n')
+		}
+	}
+	return
+}
+
+// Check if the chunk ends at a line break (\r\n, \n or \r)
+// that isn't a line continuation
+func lineEnd(chunk []byte) bool {
+	n := len(chunk)
+	if n == 0 {
+		return false
+	}
+	switch chunk[n-1] {
+	case '\n':
+		n--
+		if n > 0 && chunk[n-1] == '\r' {
+			n--
+		}
+	case '\r':
+		n--
+	default:
+		return false
+	}
+	return n == 0 || chunk[n-1] != '\\'
+}
+
+// Count the line breaks of the chunk like Parser.Next does
+func countLines(chunk []byte) (lines int) {
+	for i, c := range chunk {
+		if c == '\n' || (c == '\r' && (i+1 == len(chunk) || chunk[i+1] != '\n')) {
+			lines++
+		}
+	}
+	return
+}
+
+// Lex the chunk moving the tokens by offset lines
+func lexChunk(chunk []byte, offset int, file string, nonFinite NonFinitePolicy) chunkResult {
+	p := NewParser(bytes.NewReader(chunk))
+	p.NonFinite = nonFinite
+	tokens := make([]Token, 0, len(chunk)/4)
+	for {
+		t, err := p.NextToken()
+		if err != nil {
+			if perr, ok := err.(*ParseError); ok {
+				perr.File = file
+				perr.Pos.Line += offset
+			}
+			return chunkResult{tokens, err}
+		}
+		t.Pos.Line += offset
+		tokens = append(tokens, t)
+		if t.Kind == Eof {
+			return chunkResult{tokens, nil}
+		}
+	}
+}
+
+// Split the input in chunks and lex each one in a new goroutine,
+// with at most workers goroutines running at the same time
+func (c *chunkReader) split(r *bufio.Reader, workers, size int, nonFinite NonFinitePolicy) {
+	defer close(c.pending)
+	sem := make(chan struct{}, workers)
+	line := 0
+	for {
+		chunk, err := readChunk(r, size)
+		if err != nil && err != io.EOF {
+			res := make(chan chunkResult, 1)
+			res <- chunkResult{nil, err}
+			select {
+			case c.pending <- res:
+			case <-c.done:
+			}
+			return
+		}
+		if len(chunk) > 0 {
+			res := make(chan chunkResult, 1)
+			select {
+			case c.pending <- res:
+			case <-c.done:
+				return
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-c.done:
+				return
+			}
+			go func(chunk []byte, offset int) {
+				res <- lexChunk(chunk, offset, c.file, nonFinite)
+				<-sem
+			}(chunk, line)
+			line += countLines(chunk)
+		}
+		if err == io.EOF {
+			return
+		}
+	}
+}
+
+func (c *chunkReader) NextToken() (t Token, err error) {
+	for c.err == nil {
+		if c.head < len(c.cur.tokens) {
+			t = c.cur.tokens[c.head]
+			c.head++
+			if t.Kind == Eof {
+				// only the Eof of the last chunk is returned
+				c.eof = t
+				continue
+			}
+			return
+		}
+		if c.cur.err != nil {
+			c.err = c.cur.err
+			break
+		}
+		res, ok := <-c.pending
+		if !ok {
+			return c.eof, nil
+		}
+		c.cur, c.head = <-res, 0
+	}
+	return c.eof, c.err
+}
+
+// Stop reading the input
+func (c *chunkReader) Close() {
+	select {
+	case <-c.done:
+	default:
+		close(c.done)
+	}
+}
+
+// Load a new mesh reading the .obj contents from r, lexing the
+// contents on runtime.NumCPU() goroutines
+//
+// See LoadOptions.Workers
+func LoadMeshParallel(r io.Reader) (m *Mesh, err error) {
+	return LoadOptions{Workers: runtime.NumCPU()}.LoadMesh(r)
+}
@@ -0,0 +1,129 @@
+package wfobj
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Return a .obj file with relative and absolute indices, line
+// continuations, different line endings, objects, groups and materials
+func parallelLit(n int) string {
+	buf := &bytes.Buffer{}
+	buf.WriteString("mtllib materials.mtl\r\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(buf, "o part%v\n", i)
+		fmt.Fprintf(buf, "v %v 0.0 0.0\nv %v 1.0 0.0\r\nv %v \\\n 1.0\t1.0 0.5 0.5 0.5\rvt 0.5 0.5\n", i, i, i)
+		fmt.Fprintf(buf, "g side%v\nusemtl mat%v\ns %v\n", i%3, i%2, i%4)
+		fmt.Fprintf(buf, "f -3/-1 -2/-1 -1/-1\n# comment %v\nf %v %v \\\r\n%v\n", i, 3*i+1, 3*i+2, 3*i+3)
+		fmt.Fprintf(buf, "p -1\nl -3 -2\n")
+	}
+	return buf.String()
+}
+
+func TestChunkReader(t *testing.T) {
+	lit := parallelLit(50)
+	expected, err := load(NewLiteralParser(lit), LoadOptions{})
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	for _, size := range []int{1, 7, 64, 1000, len(lit) + 1} {
+		c := newChunkReader(strings.NewReader(lit), "", 4, size, RejectNonFinite)
+		got, err := load(c, LoadOptions{})
+		c.Close()
+		if err != nil {
+			t.Fatalf("Unable to load mesh with chunks of %v bytes: %v", size, err)
+		}
+		if !reflect.DeepEqual(got.mesh, expected.mesh) || !reflect.DeepEqual(got.objects, expected.objects) {
+			t.Errorf("Mesh loaded with chunks of %v bytes is different", size)
+		}
+	}
+
+	// all the tokens have the same position, also with lone \r line endings
+	for _, lit := range []string{lit, strings.Replace(lit, "\n", "\r", -1)} {
+		p := NewLiteralParser(lit)
+		c := newChunkReader(strings.NewReader(lit), "", 3, 16, RejectNonFinite)
+		for {
+			want, _ := p.NextToken()
+			got, err := c.NextToken()
+			if err != nil {
+				t.Fatalf("Unable to read token: %v", err)
+			}
+			if got != want {
+				t.Fatalf("Expecting %v at %v got %v at %v", &want, &want.Pos, &got, &got.Pos)
+			}
+			if got.Kind == Eof {
+				break
+			}
+		}
+		c.Close()
+	}
+}
+
+func TestReadChunk(t *testing.T) {
+	for _, lit := range []string{
+		strings.Repeat("v 0 0 0\r", 100),
+		strings.Repeat("v 0 0 0\r\n", 100),
+		strings.Repeat("v 0 0 \\\r0\r", 100),
+	} {
+		r := bufio.NewReader(strings.NewReader(lit))
+		var chunks []string
+		for {
+			chunk, err := readChunk(r, 8)
+			if len(chunk) > 0 {
+				chunks = append(chunks, string(chunk))
+			}
+			if err != nil {
+				break
+			}
+		}
+		if len(chunks) != 100 || strings.Join(chunks, "") != lit {
+			t.Errorf("Expecting 100 chunks of %q got %q", lit[:len(lit)/100], chunks)
+		}
+	}
+}
+
+func TestChunkReaderErrors(t *testing.T) {
+	for _, lit := range []string{
+		parallelLit(20) + "v 1.0 x 2.0\n" + parallelLit(5),
+		parallelLit(20) + "f 1 2 1000\n" + parallelLit(5),
+		parallelLit(20) + "usemtl caf\xe9\n" + parallelLit(20),
+		"",
+	} {
+		_, want := load(NewLiteralParser(lit), LoadOptions{Strict: true})
+		if want == nil && len(lit) > 0 {
+			t.Errorf("Expecting an error for %q", lit)
+		}
+		c := newChunkReader(strings.NewReader(lit), "", 4, 32, RejectNonFinite)
+		_, got := load(c, LoadOptions{Strict: true})
+		c.Close()
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Expecting error %v got %v", want, got)
+		}
+	}
+}
+
+func TestLoadMeshParallel(t *testing.T) {
+	want, err := LoadMeshFromFile("cube.obj")
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	got, err := LoadOptions{Workers: 4}.LoadMeshFromFile("cube.obj")
+	if err != nil {
+		t.Fatalf("Unable to load mesh in parallel: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Mesh loaded in parallel is different")
+	}
+
+	lit := parallelLit(10)
+	if got, err = LoadMeshParallel(strings.NewReader(lit)); err != nil {
+		t.Fatalf("Unable to load mesh in parallel: %v", err)
+	}
+	if want, _ = LoadMeshFromReader(strings.NewReader(lit)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Mesh loaded in parallel is different")
+	}
+}
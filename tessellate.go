@@ -0,0 +1,495 @@
+package wfobj
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Returned (wrapped) when a curve or surface can't be tessellated
+var ErrInvalidFreeForm = errors.New("invalid free-form geometry")
+
+// Upper limit of the segments of each polynomial span
+const maxSegments = 1024
+
+// Polynomial basis of a free-form element in one direction
+type basis struct {
+	typ    CurveType
+	degree int
+	// parm values, the knot vector of b-splines
+	params []float64
+	// number of control points in this direction
+	count int
+}
+
+// Return the number of control points implied by the parameters
+func basisCount(typ CurveType, degree, params int) int {
+	switch typ {
+	case Bezier:
+		return (params-1)*degree + 1
+	case BSpline:
+		return params - degree - 1
+	case Cardinal:
+		return params + 2
+	}
+	return 0
+}
+
+// Create the basis of the element and check if it is valid
+// for the number of control points
+func newBasis(typ CurveType, degree int, params []float32, count int) (b *basis, err error) {
+	switch typ {
+	case Bezier, BSpline, Cardinal:
+	default:
+		return nil, fmt.Errorf("%w: unsupported curve type %v", ErrInvalidFreeForm, typ)
+	}
+	if degree < 1 || (typ == Cardinal && degree != 3) {
+		return nil, fmt.Errorf("%w: invalid degree %v for %v", ErrInvalidFreeForm, degree, typ)
+	}
+	if len(params) < 2 || basisCount(typ, degree, len(params)) != count {
+		return nil, fmt.Errorf("%w: %v parameters don't match %v control points of degree %v %v",
+			ErrInvalidFreeForm, len(params), count, degree, typ)
+	}
+	b = &basis{typ, degree, make([]float64, len(params)), count}
+	for i, p := range params {
+		b.params[i] = float64(p)
+		if i > 0 && b.params[i] < b.params[i-1] {
+			return nil, fmt.Errorf("%w: decreasing parameters %v", ErrInvalidFreeForm, params)
+		}
+	}
+	return
+}
+
+// Return the parameter range where the basis is defined
+func (b *basis) domain() (float64, float64) {
+	if b.typ == BSpline {
+		return b.params[b.degree], b.params[b.count]
+	}
+	return b.params[0], b.params[len(b.params)-1]
+}
+
+// Return the distinct parameters that split the domain in polynomial spans
+func (b *basis) breaks() (ts []float64) {
+	lo, hi := b.domain()
+	for _, p := range b.params {
+		if p >= lo && p <= hi && (len(ts) == 0 || p > ts[len(ts)-1]) {
+			ts = append(ts, p)
+		}
+	}
+	return
+}
+
+// Return the span of params that contains t, between first and last
+func findSpan(params []float64, t float64, first, last int) int {
+	s := sort.Search(last-first+1, func(i int) bool { return params[first+i+1] > t }) + first
+	if s > last {
+		s = last
+	}
+	// skip empty spans at the end of the domain
+	for s > first && params[s] == params[s+1] {
+		s--
+	}
+	return s
+}
+
+// Return the local parameter of t inside the span [t0, t1]
+func local(t, t0, t1 float64) float64 {
+	if t1 == t0 {
+		return 0
+	}
+	return (t - t0) / (t1 - t0)
+}
+
+// Return the index of the first control point that affects
+// the element at t and the weight of each affected point
+func (b *basis) eval(t float64) (first int, w []float64) {
+	w = make([]float64, b.degree+1)
+	switch b.typ {
+	case Bezier:
+		s := findSpan(b.params, t, 0, len(b.params)-2)
+		u := local(t, b.params[s], b.params[s+1])
+		for i, _ := range w {
+			w[i] = binomial(b.degree, i) * math.Pow(u, float64(i)) * math.Pow(1-u, float64(b.degree-i))
+		}
+		first = s * b.degree
+
+	case BSpline:
+		// Cox-de Boor recursion, see The NURBS Book A2.2
+		k := b.params
+		s := findSpan(k, t, b.degree, b.count-1)
+		left := make([]float64, b.degree+1)
+		right := make([]float64, b.degree+1)
+		w[0] = 1
+		for j := 1; j <= b.degree; j++ {
+			left[j] = t - k[s+1-j]
+			right[j] = k[s+j] - t
+			saved := 0.0
+			for r := 0; r < j; r++ {
+				temp := 0.0
+				if d := right[r+1] + left[j-r]; d != 0 {
+					temp = w[r] / d
+				}
+				w[r] = saved + right[r+1]*temp
+				saved = left[j-r] * temp
+			}
+			w[j] = saved
+		}
+		first = s - b.degree
+
+	case Cardinal:
+		// Catmull-Rom spline through the inner control points
+		s := findSpan(b.params, t, 0, len(b.params)-2)
+		u := local(t, b.params[s], b.params[s+1])
+		u2, u3 := u*u, u*u*u
+		w[0] = (-u3 + 2*u2 - u) / 2
+		w[1] = (3*u3 - 5*u2 + 2) / 2
+		w[2] = (-3*u3 + 4*u2 + u) / 2
+		w[3] = (u3 - u2) / 2
+		first = s
+	}
+	return
+}
+
+func binomial(n, k int) float64 {
+	r := 1.0
+	for i := 1; i <= k; i++ {
+		r = r * float64(n-k+i) / float64(i)
+	}
+	return r
+}
+
+// Sum of weighted points in homogeneous coordinates
+type weightedSum struct {
+	x, y, z, w float64
+}
+
+func (s *weightedSum) add(v Vertex, w float64) {
+	s.x += float64(v.X) * w
+	s.y += float64(v.Y) * w
+	s.z += float64(v.Z) * w
+	s.w += w
+}
+
+// Return the projection of the sum
+func (s *weightedSum) vertex() Vertex {
+	if s.w == 0 {
+		return Vertex{}
+	}
+	return Vertex{float32(s.x / s.w), float32(s.y / s.w), float32(s.z / s.w)}
+}
+
+// Return the weight of each control point, all one if the element isn't rational
+func rationalWeights(ff *FreeForm, weights []float32, count int) []float64 {
+	r := make([]float64, count)
+	for i, _ := range r {
+		r[i] = 1
+		if ff.Rational && i < len(weights) {
+			r[i] = float64(weights[i])
+		}
+	}
+	return r
+}
+
+// Return the number of segments used to approximate the span [t0, t1] of f
+func spanSegments(f func(float64) Vertex, t0, t1 float64, degree int, tech *Technique, res float32) int {
+	n := 1
+	switch tech.Kind {
+	case NoTechnique:
+		n = DefaultResolution * degree
+	case ParamTechnique, ParamBTechnique:
+		n = int(math.Ceil(float64(res) * float64(degree)))
+	case SpaceTechnique:
+		if tech.MaxLength > 0 {
+			length := 0.0
+			prev := f(t0)
+			for i := 1; i <= 16; i++ {
+				cur := f(t0 + (t1-t0)*float64(i)/16)
+				length += float64(cur.Distance(prev))
+				prev = cur
+			}
+			n = int(math.Ceil(length / float64(tech.MaxLength)))
+		}
+	case CurvatureTechnique:
+		for n < maxSegments && !flatEnough(f, t0, t1, n, tech) {
+			n *= 2
+		}
+	}
+	if n < 1 {
+		n = 1
+	}
+	if n > maxSegments {
+		n = maxSegments
+	}
+	return n
+}
+
+// Check if n segments approximate the span [t0, t1] of f within
+// the maximum distance and angle of the technique
+func flatEnough(f func(float64) Vertex, t0, t1 float64, n int, tech *Technique) bool {
+	maxCos := math.Cos(float64(tech.MaxAngle) * math.Pi / 180)
+	var prevDir Vertex
+	for i := 0; i < n; i++ {
+		a := t0 + (t1-t0)*float64(i)/float64(n)
+		b := t0 + (t1-t0)*float64(i+1)/float64(n)
+		pa, pb, mid := f(a), f(b), f((a+b)/2)
+		if tech.MaxDistance > 0 && mid.Distance(pa.Lerp(pb, 0.5)) > tech.MaxDistance {
+			return false
+		}
+		dir := pb.Sub(pa).Normalize()
+		if tech.MaxAngle > 0 && i > 0 && float64(dir.Dot(prevDir)) < maxCos {
+			return false
+		}
+		prevDir = dir
+	}
+	return true
+}
+
+// Return the parameters where the element is sampled between from and to
+//
+// f is evaluated to measure the element, each span may need a
+// different number of segments. If from > to the parameters decrease
+func sampleParams(b *basis, from, to float64, f func(float64) Vertex, tech *Technique, res float32) []float64 {
+	lo, hi := math.Min(from, to), math.Max(from, to)
+	dlo, dhi := b.domain()
+	lo, hi = math.Max(lo, dlo), math.Min(hi, dhi)
+	ts := []float64{lo}
+	breaks := b.breaks()
+	for i := 0; i+1 < len(breaks); i++ {
+		t0, t1 := math.Max(breaks[i], lo), math.Min(breaks[i+1], hi)
+		if t0 >= t1 {
+			continue
+		}
+		n := spanSegments(f, t0, t1, b.degree, tech, res)
+		for j := 1; j <= n; j++ {
+			ts = append(ts, t0+(t1-t0)*float64(j)/float64(n))
+		}
+	}
+	if from > to {
+		for i, j := 0, len(ts)-1; i < j; i, j = i+1, j-1 {
+			ts[i], ts[j] = ts[j], ts[i]
+		}
+	}
+	return ts
+}
+
+// Return a function that evaluates the curve
+func curveFunc(b *basis, cps VertexList, weights []float64) func(float64) Vertex {
+	return func(t float64) Vertex {
+		first, w := b.eval(t)
+		sum := weightedSum{}
+		for i, wi := range w {
+			sum.add(cps[first+i], wi*weights[first+i])
+		}
+		return sum.vertex()
+	}
+}
+
+// Return the polyline that approximates the curve
+func tessellateCurve(c *Curve) (l Line, err error) {
+	b, err := newBasis(c.Type, c.DegreeU, c.ParamsU, len(c.ControlPoints))
+	if err != nil {
+		return
+	}
+	f := curveFunc(b, c.ControlPoints, rationalWeights(&c.FreeForm, c.Weights, len(c.ControlPoints)))
+	ts := sampleParams(b, float64(c.Start), float64(c.End), f, &c.Technique, c.Technique.ResolutionU)
+	l.Vertices = make(VertexList, len(ts))
+	l.TexCoords = make(VertexList, 0)
+	for i, t := range ts {
+		l.Vertices[i] = f(t)
+	}
+	return
+}
+
+// Return the polygon of a trimming loop in the parameter space of the surface
+func trimPolygon(loop Loop, curves []Curve2D) ([]point2, error) {
+	pts := make([]point2, 0)
+	for _, ref := range loop {
+		c := &curves[ref.Curve]
+		b, err := newBasis(c.Type, c.DegreeU, c.ParamsU, len(c.ControlPoints))
+		if err != nil {
+			return nil, err
+		}
+		weights := make([]float64, len(c.ControlPoints))
+		cps := make(VertexList, len(c.ControlPoints))
+		for i, cp := range c.ControlPoints {
+			weights[i] = 1
+			if c.Rational {
+				weights[i] = float64(cp.Z)
+			}
+			cps[i] = Vertex{cp.X, cp.Y, 0}
+		}
+		f := curveFunc(b, cps, weights)
+		for _, t := range sampleParams(b, float64(ref.Start), float64(ref.End), f, &Technique{}, 0) {
+			p := f(t)
+			pts = append(pts, point2{float64(p.X), float64(p.Y)})
+		}
+	}
+	return pts, nil
+}
+
+// Check if p is inside the polygon using the even-odd rule
+func inPolygon(p point2, poly []point2) (in bool) {
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			in = !in
+		}
+	}
+	return
+}
+
+// Check if p is inside any of the polygons
+func inAnyPolygon(p point2, polys [][]point2) bool {
+	for _, poly := range polys {
+		if inPolygon(p, poly) {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the polygons of the loops
+func trimPolygons(loops []Loop, curves []Curve2D) (polys [][]point2, err error) {
+	for _, loop := range loops {
+		poly, err := trimPolygon(loop, curves)
+		if err != nil {
+			return nil, err
+		}
+		polys = append(polys, poly)
+	}
+	return
+}
+
+// Return the quads that approximate the surface
+//
+// A quad is kept if the center of its parameters is inside a trim
+// loop (when there is any) and outside all the holes
+func tessellateSurface(s *Surface, curves []Curve2D) ([]Face, error) {
+	nu := basisCount(s.Type, s.DegreeU, len(s.ParamsU))
+	nv := basisCount(s.Type, s.DegreeV, len(s.ParamsV))
+	bu, err := newBasis(s.Type, s.DegreeU, s.ParamsU, nu)
+	if err != nil {
+		return nil, err
+	}
+	bv, err := newBasis(s.Type, s.DegreeV, s.ParamsV, nv)
+	if err != nil {
+		return nil, err
+	}
+	if nu*nv != len(s.ControlPoints) {
+		return nil, fmt.Errorf("%w: expecting %vx%v control points but got %v",
+			ErrInvalidFreeForm, nu, nv, len(s.ControlPoints))
+	}
+	trims, err := trimPolygons(s.Trims, curves)
+	if err != nil {
+		return nil, err
+	}
+	holes, err := trimPolygons(s.Holes, curves)
+	if err != nil {
+		return nil, err
+	}
+
+	weights := rationalWeights(&s.FreeForm, s.Weights, len(s.ControlPoints))
+	// interpolate the control point information at (u, v)
+	eval := func(list VertexList, u, v float64) Vertex {
+		fu, wu := bu.eval(u)
+		fv, wv := bv.eval(v)
+		sum := weightedSum{}
+		for j, wj := range wv {
+			for i, wi := range wu {
+				idx := (fv+j)*nu + fu + i
+				sum.add(list[idx], wi*wj*weights[idx])
+			}
+		}
+		return sum.vertex()
+	}
+
+	// measure the iso-curves at the span breaks and middles
+	isoParams := func(b *basis) (ts []float64) {
+		breaks := b.breaks()
+		for i, t := range breaks {
+			ts = append(ts, t)
+			if i+1 < len(breaks) {
+				ts = append(ts, (t+breaks[i+1])/2)
+			}
+		}
+		return
+	}
+	var us, vs []float64
+	for _, v := range isoParams(bv) {
+		f := func(u float64) Vertex { return eval(s.ControlPoints, u, v) }
+		if ts := sampleParams(bu, float64(s.StartU), float64(s.EndU), f, &s.Technique, s.Technique.ResolutionU); len(ts) > len(us) {
+			us = ts
+		}
+	}
+	for _, u := range isoParams(bu) {
+		f := func(v float64) Vertex { return eval(s.ControlPoints, u, v) }
+		if ts := sampleParams(bv, float64(s.StartV), float64(s.EndV), f, &s.Technique, s.Technique.ResolutionV); len(ts) > len(vs) {
+			vs = ts
+		}
+	}
+
+	faces := make([]Face, 0)
+	for j := 0; j+1 < len(vs); j++ {
+		for i := 0; i+1 < len(us); i++ {
+			center := point2{(us[i] + us[i+1]) / 2, (vs[j] + vs[j+1]) / 2}
+			if (len(trims) > 0 && !inAnyPolygon(center, trims)) || inAnyPolygon(center, holes) {
+				continue
+			}
+			corners := [4][2]float64{{us[i], vs[j]}, {us[i+1], vs[j]}, {us[i+1], vs[j+1]}, {us[i], vs[j+1]}}
+			f := Face{
+				Vertices:  make(VertexList, 4),
+				Normals:   make(VertexList, 0),
+				TexCoords: make(VertexList, 0),
+				Material:  s.Material,
+			}
+			for k, c := range corners {
+				f.Vertices[k] = eval(s.ControlPoints, c[0], c[1])
+				if len(s.Normals) == len(s.ControlPoints) {
+					f.Normals = append(f.Normals, eval(s.Normals, c[0], c[1]).Normalize())
+				}
+				if len(s.TexCoords) == len(s.ControlPoints) {
+					f.TexCoords = append(f.TexCoords, eval(s.TexCoords, c[0], c[1]))
+				}
+			}
+			faces = append(faces, f)
+		}
+	}
+	return faces, nil
+}
+
+// Append the polylines that approximate the free-form curves to the
+// lines of the mesh and the polygons that approximate the surfaces
+// to its faces
+//
+// Bezier, B-spline and Cardinal elements are supported, rational ones
+// use the w of their control points as weights. The number of segments
+// of each polynomial span follows the ctech and stech statements, or
+// DefaultResolution times the degree if there is none.
+// Surfaces are split in quads, without normals or texture coordinates
+// unless the surface has one for each control point. Special curves
+// are ignored.
+//
+// If an element is invalid or unsupported the mesh isn't changed and
+// the returned error wraps ErrInvalidFreeForm
+func (m *Mesh) Tessellate() error {
+	lines := make([]Line, 0)
+	for i, _ := range m.FreeForm.Curves {
+		l, err := tessellateCurve(&m.FreeForm.Curves[i])
+		if err != nil {
+			return fmt.Errorf("curve %v: %w", i+1, err)
+		}
+		lines = append(lines, l)
+	}
+	faces := make([]Face, 0)
+	for i, _ := range m.FreeForm.Surfaces {
+		f, err := tessellateSurface(&m.FreeForm.Surfaces[i], m.FreeForm.Curves2D)
+		if err != nil {
+			return fmt.Errorf("surface %v: %w", i+1, err)
+		}
+		faces = append(faces, f...)
+	}
+	m.Lines = append(m.Lines, lines...)
+	m.Faces = append(m.Faces, faces...)
+	return nil
+}
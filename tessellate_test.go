@@ -0,0 +1,178 @@
+package wfobj
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func loadFreeForm(t *testing.T, lit string) *Mesh {
+	m, err := LoadMeshFromReader(strings.NewReader(lit))
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	if err = m.Tessellate(); err != nil {
+		t.Fatalf("Unable to tessellate mesh: %v", err)
+	}
+	return m
+}
+
+func TestTessellateCurves(t *testing.T) {
+	// the same cubic as bezier, clamped b-spline and rational bezier with unit weights
+	m := loadFreeForm(t, `v 0.0 0.0 0.0
+v 1.0 2.0 0.0
+v 3.0 2.0 0.0
+v 4.0 0.0 0.0
+cstype bezier
+deg 3
+curv 0.0 1.0 1 2 3 4
+parm u 0.0 1.0
+end
+cstype bspline
+ctech cparm 2
+curv 0.0 1.0 1 2 3 4
+parm u 0.0 0.0 0.0 0.0 1.0 1.0 1.0 1.0
+end
+`)
+	if len(m.Lines) != 2 || len(m.Faces) != 0 {
+		t.Fatalf("Expecting 2 lines got %v lines and %v faces", len(m.Lines), len(m.Faces))
+	}
+	bezier, bspline := m.Lines[0].Vertices, m.Lines[1].Vertices
+	if len(bezier) != 3*DefaultResolution+1 || len(bspline) != 3*2+1 {
+		t.Fatalf("Expecting %v and %v vertices got %v and %v", 3*DefaultResolution+1, 3*2+1, len(bezier), len(bspline))
+	}
+	if !bezier[0].Same(Vertex{0, 0, 0}) || !bezier[len(bezier)-1].Same(Vertex{4, 0, 0}) {
+		t.Errorf("Bezier must start and end at the control points %v %v", bezier[0], bezier[len(bezier)-1])
+	}
+	// B(0.5) = (P1 + 3 P2 + 3 P3 + P4) / 8
+	mid := Vertex{2, 1.5, 0}
+	if !bezier[len(bezier)/2].ApproxEqual(mid, 1e-5) || !bspline[len(bspline)/2].ApproxEqual(mid, 1e-5) {
+		t.Errorf("Expecting %v in the middle got %v and %v", mid, bezier[len(bezier)/2], bspline[len(bspline)/2])
+	}
+}
+
+func TestTessellateRationalCurve(t *testing.T) {
+	// quarter of a unit circle
+	m := loadFreeForm(t, `v 1.0 0.0 0.0
+v 1.0 1.0 0.0 0.70710678
+v 0.0 1.0 0.0
+cstype rat bezier
+deg 2
+curv 0.0 1.0 1 2 3
+parm u 0.0 1.0
+end
+`)
+	for _, v := range m.Lines[0].Vertices {
+		if r := v.Length(); math.Abs(float64(r)-1) > 1e-5 {
+			t.Errorf("Expecting a point of the circle got %v", v)
+		}
+	}
+}
+
+func TestTessellateCardinal(t *testing.T) {
+	m := loadFreeForm(t, `v 0.0 0.0 0.0
+v 1.0 1.0 0.0
+v 2.0 0.0 0.0
+v 3.0 1.0 0.0
+v 4.0 0.0 0.0
+cstype cardinal
+deg 3
+ctech cparm 1
+curv 0.0 2.0 1 2 3 4 5
+parm u 0.0 1.0 2.0
+end
+`)
+	// the curve goes through the inner control points
+	expected := VertexList{{1, 1, 0}, {2, 0, 0}, {3, 1, 0}}
+	got := VertexList{m.Lines[0].Vertices[0], m.Lines[0].Vertices[3], m.Lines[0].Vertices[6]}
+	for i, _ := range expected {
+		if !got[i].ApproxEqual(expected[i], 1e-5) {
+			t.Errorf("Expecting %v got %v", expected[i], got[i])
+		}
+	}
+}
+
+func TestTessellateSurface(t *testing.T) {
+	m := loadFreeForm(t, freeformlit)
+	full := (3 * DefaultResolution) * (3 * DefaultResolution)
+	surface := m.Faces
+	if len(surface) == 0 || len(surface) >= full {
+		t.Fatalf("Expecting a trimmed surface with less than %v quads got %v", full, len(surface))
+	}
+	for _, f := range surface {
+		if len(f.Vertices) != 4 {
+			t.Fatalf("Expecting quads got %v", f)
+		}
+		for _, v := range f.Vertices {
+			if v.X < 0.1*3-0.2 || v.X > 0.9*3+0.2 {
+				t.Errorf("Vertex %v outside of the trimming loop", v)
+			}
+		}
+	}
+
+	// without trimming loops the corners of the patch are the control points
+	m.Faces = nil
+	m.Lines = nil
+	m.FreeForm.Surfaces[0].Trims = nil
+	m.FreeForm.Surfaces[0].Holes = nil
+	m.FreeForm.Curves = nil
+	if err := m.Tessellate(); err != nil {
+		t.Fatalf("Unable to tessellate mesh: %v", err)
+	}
+	if len(m.Faces) != full {
+		t.Fatalf("Expecting %v quads got %v", full, len(m.Faces))
+	}
+	if !m.Faces[0].Vertices[0].Same(Vertex{0, 0, 0}) || !m.Faces[full-1].Vertices[2].Same(Vertex{3, 3, 0}) {
+		t.Errorf("Invalid corners %v %v", m.Faces[0].Vertices[0], m.Faces[full-1].Vertices[2])
+	}
+}
+
+func TestTessellateSurfaceTechnique(t *testing.T) {
+	patch := `v 0.0 0.0 0.0
+v 1.0 0.0 0.0
+v 0.0 1.0 0.0
+v 1.0 1.0 0.0
+cstype bezier
+deg 1 1
+%v
+surf 0.0 1.0 0.0 1.0 1 2 3 4
+parm u 0.0 1.0
+parm v 0.0 1.0
+end
+`
+	for _, test := range []struct {
+		tech  string
+		quads int
+	}{
+		{"", DefaultResolution * DefaultResolution},
+		{"stech cparma 2 3", 2 * 3},
+		{"stech cparmb 5", 5 * 5},
+	} {
+		m := loadFreeForm(t, fmt.Sprintf(patch, test.tech))
+		if len(m.Faces) != test.quads {
+			t.Errorf("%q: expecting %v quads got %v", test.tech, test.quads, len(m.Faces))
+		}
+	}
+}
+
+func TestTessellateErrors(t *testing.T) {
+	m, err := LoadMeshFromReader(strings.NewReader(`v 0.0 0.0 0.0
+v 1.0 0.0 0.0
+cstype taylor
+deg 1
+curv 0.0 1.0 1 2
+parm u 0.0 1.0
+end
+`))
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	if err = m.Tessellate(); !errors.Is(err, ErrInvalidFreeForm) {
+		t.Errorf("Expecting ErrInvalidFreeForm got %v", err)
+	}
+	if len(m.Faces) != 0 {
+		t.Errorf("Mesh must not change on errors")
+	}
+}
@@ -151,7 +151,7 @@ func onMousePos(x, y int) {
 	if globalState.Drag.IsDrag {
 		//		println("Start: ", fmt.Sprintf("%v", globalState.Drag.Start))
 		//		println("End: ", fmt.Sprintf("%v", globalState.Drag.End))
-		//		println("Sub: ", fmt.Sprintf("%v", globalState.Drag.End.Sub(&globalState.Drag.Start)))
+		//		println("Sub: ", fmt.Sprintf("%v", globalState.Drag.End.Sub(globalState.Drag.Start)))
 		globalState.Drag.End.X = float32(x)
 		globalState.Drag.End.Y = float32(y)
 	}
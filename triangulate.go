@@ -0,0 +1,217 @@
+package wfobj
+
+import (
+	"math"
+)
+
+// A 2D point used to triangulate a polygon projected on its plane
+type point2 struct {
+	X, Y float64
+}
+
+// Return the z component of the cross product of (b - a) and (c - b)
+func turn(a, b, c point2) float64 {
+	return (b.X-a.X)*(c.Y-b.Y) - (b.Y-a.Y)*(c.X-b.X)
+}
+
+// Check if p is inside or on the edges of the triangle abc
+// with the given orientation
+func inTriangle(p, a, b, c point2, sign float64) bool {
+	return turn(a, b, p)*sign >= 0 && turn(b, c, p)*sign >= 0 && turn(c, a, p)*sign >= 0
+}
+
+// Project the polygon on the plane of its normal
+//
+// Return the projected points and the sign of the polygon orientation,
+// zero if the polygon is degenerated
+func project(vertices VertexList) (pts []point2, sign float64) {
+	// Newell's method handles concave and non-planar polygons
+	var nx, ny, nz float64
+	for i, _ := range vertices {
+		cur := &vertices[i]
+		next := &vertices[(i+1)%len(vertices)]
+		nx += float64(cur.Y-next.Y) * float64(cur.Z+next.Z)
+		ny += float64(cur.Z-next.Z) * float64(cur.X+next.X)
+		nz += float64(cur.X-next.X) * float64(cur.Y+next.Y)
+	}
+
+	// drop the dominant axis keeping the axes in cyclic order
+	// so the orientation is given by the sign of the dropped one
+	ax, ay, az := math.Abs(nx), math.Abs(ny), math.Abs(nz)
+	pts = make([]point2, len(vertices))
+	for i, v := range vertices {
+		switch {
+		case az >= ax && az >= ay:
+			pts[i] = point2{float64(v.X), float64(v.Y)}
+			sign = nz
+		case ax >= ay:
+			pts[i] = point2{float64(v.Y), float64(v.Z)}
+			sign = nx
+		default:
+			pts[i] = point2{float64(v.Z), float64(v.X)}
+			sign = ny
+		}
+	}
+	switch {
+	case sign > 0:
+		sign = 1
+	case sign < 0:
+		sign = -1
+	}
+	return
+}
+
+// Check if all the corners of the polygon turn to the same side
+func convex(pts []point2, sign float64) bool {
+	n := len(pts)
+	for i, _ := range pts {
+		if turn(pts[(i+n-1)%n], pts[i], pts[(i+1)%n])*sign < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Return the triangles of a fan around the first vertex of the polygon
+func fan(indices []int) (tris [][3]int) {
+	for i := 1; i+1 < len(indices); i++ {
+		tris = append(tris, [3]int{indices[0], indices[i], indices[i+1]})
+	}
+	return
+}
+
+// Return the triangles of the polygon using the ear clipping method
+//
+// If no ear can be found (ie, self-intersecting polygons)
+// the remaining vertices are triangulated as a fan
+func clipEars(pts []point2, sign float64) (tris [][3]int) {
+	remaining := make([]int, len(pts))
+	for i, _ := range remaining {
+		remaining[i] = i
+	}
+
+	for len(remaining) > 3 {
+		n := len(remaining)
+		ear := -1
+		for i := 0; i < n && ear == -1; i++ {
+			prev, cur, next := remaining[(i+n-1)%n], remaining[i], remaining[(i+1)%n]
+			a, b, c := pts[prev], pts[cur], pts[next]
+			if turn(a, b, c)*sign <= 0 {
+				// reflex or degenerated corner
+				continue
+			}
+			ear = i
+			for _, j := range remaining {
+				if j == prev || j == cur || j == next {
+					continue
+				}
+				p := pts[j]
+				if p == a || p == b || p == c {
+					continue
+				}
+				if inTriangle(p, a, b, c, sign) {
+					ear = -1
+					break
+				}
+			}
+		}
+		if ear == -1 {
+			return append(tris, fan(remaining)...)
+		}
+		tris = append(tris, [3]int{remaining[(ear+n-1)%n], remaining[ear], remaining[(ear+1)%n]})
+		remaining = append(remaining[:ear], remaining[ear+1:]...)
+	}
+	return append(tris, [3]int{remaining[0], remaining[1], remaining[2]})
+}
+
+// Return the triangles of the face as indices of its vertices
+func triangulateFace(f *Face) [][3]int {
+	pts, sign := project(f.Vertices)
+	indices := make([]int, len(pts))
+	for i, _ := range indices {
+		indices[i] = i
+	}
+	if sign == 0 || convex(pts, sign) {
+		return fan(indices)
+	}
+	return clipEars(pts, sign)
+}
+
+// Return a copy of the lists of the face with only the elements at idx
+//
+// Normals, texture coordinates, weights and colors are
+// only copied if there is one for each vertex
+func subFace(f *Face, idx [3]int) Face {
+	sub := *f
+	sub.Vertices = VertexList{f.Vertices[idx[0]], f.Vertices[idx[1]], f.Vertices[idx[2]]}
+	sub.Normals = make(VertexList, 0)
+	sub.TexCoords = make(VertexList, 0)
+	sub.Weights = nil
+	sub.Colors = nil
+	if len(f.Weights) == len(f.Vertices) {
+		sub.Weights = []float32{f.Weights[idx[0]], f.Weights[idx[1]], f.Weights[idx[2]]}
+	}
+	if len(f.Colors) == len(f.Vertices) {
+		sub.Colors = []Color{f.Colors[idx[0]], f.Colors[idx[1]], f.Colors[idx[2]]}
+	}
+	if len(f.Normals) == len(f.Vertices) {
+		sub.Normals = VertexList{f.Normals[idx[0]], f.Normals[idx[1]], f.Normals[idx[2]]}
+	}
+	if len(f.TexCoords) == len(f.Vertices) {
+		sub.TexCoords = VertexList{f.TexCoords[idx[0]], f.TexCoords[idx[1]], f.TexCoords[idx[2]]}
+	}
+	return sub
+}
+
+// Split the faces in triangles and return the index of the
+// first triangle of each face, followed by the new number of faces
+func (m *Mesh) triangulate() []int {
+	offsets := make([]int, len(m.Faces)+1)
+	faces := make([]Face, 0, len(m.Faces))
+	for i, _ := range m.Faces {
+		f := &m.Faces[i]
+		offsets[i] = len(faces)
+		if len(f.Vertices) <= 3 {
+			faces = append(faces, *f)
+			continue
+		}
+		for _, tri := range triangulateFace(f) {
+			faces = append(faces, subFace(f, tri))
+		}
+	}
+	offsets[len(m.Faces)] = len(faces)
+	m.Faces = faces
+	return offsets
+}
+
+// Split the faces with more than three vertices in triangles
+//
+// Convex faces are split as a fan, concave and non-planar ones using
+// ear clipping. Each triangle keeps the normals, texture coordinates,
+// weights, colors, material and smoothing group of its face. Faces with less than
+// three vertices are kept as is
+func (m *Mesh) Triangulate() {
+	m.triangulate()
+}
+
+// Move the ranges to the triangles of the faces
+func remapRanges(ranges []FaceRange, offsets []int) {
+	for i, _ := range ranges {
+		ranges[i].Start = offsets[ranges[i].Start]
+		ranges[i].End = offsets[ranges[i].End]
+	}
+}
+
+// Split the faces of the model in triangles, updating
+// the ranges of the objects and groups
+//
+// See Mesh.Triangulate
+func (m *Model) Triangulate() {
+	offsets := m.Mesh.triangulate()
+	for _, o := range m.Objects {
+		remapRanges(o.Ranges, offsets)
+		for _, g := range o.Groups {
+			remapRanges(g.Ranges, offsets)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package wfobj
+
+import (
+	"math"
+	"testing"
+)
+
+// Return the signed area of the triangle projected on the xy plane
+func signedArea(a, b, c Vertex) float64 {
+	return (float64(b.X-a.X)*float64(c.Y-a.Y) - float64(b.Y-a.Y)*float64(c.X-a.X)) / 2
+}
+
+func TestTriangulate(t *testing.T) {
+	red := NewMaterial("Red")
+	// L shaped polygon, concave at (1, 1)
+	concave := VertexList{
+		Vertex{0, 0, 0}, Vertex{2, 0, 0}, Vertex{2, 1, 0},
+		Vertex{1, 1, 0}, Vertex{1, 2, 0}, Vertex{0, 2, 0},
+	}
+	quad := VertexList{Vertex{0, 0, 1}, Vertex{1, 0, 1}, Vertex{1, 1, 1}, Vertex{0, 1, 1}}
+	m := &Mesh{
+		Faces: []Face{
+			Face{Vertices: quad, TexCoords: quad, Normals: VertexList{}, Material: red, SmoothingGroup: 3},
+			Face{Vertices: concave},
+			Face{Vertices: VertexList{Vertex{0, 0, 0}, Vertex{1, 1, 1}}},
+		},
+	}
+	m.Triangulate()
+
+	if len(m.Faces) != 2+4+1 {
+		t.Fatalf("Expecting 7 faces but got %v", len(m.Faces))
+	}
+	for i := 0; i < 2; i++ {
+		f := &m.Faces[i]
+		if f.Material != red || f.SmoothingGroup != 3 {
+			t.Errorf("Triangle %v lost the material or smoothing group", i)
+		}
+		if !f.TexCoords.Same(f.Vertices) || len(f.Normals) != 0 {
+			t.Errorf("Triangle %v has invalid texture coordinates or normals %v", i, f)
+		}
+	}
+
+	area := 0.0
+	for i := 2; i < 6; i++ {
+		f := &m.Faces[i]
+		if len(f.Vertices) != 3 {
+			t.Fatalf("Expecting a triangle but got %v", f.Vertices)
+		}
+		a := signedArea(f.Vertices[0], f.Vertices[1], f.Vertices[2])
+		if a <= 0 {
+			t.Errorf("Triangle %v has the wrong orientation", f.Vertices)
+		}
+		area += a
+	}
+	if math.Abs(area-3) > 1e-6 {
+		t.Errorf("Expecting the triangles to cover an area of 3 but got %v", area)
+	}
+
+	if len(m.Faces[6].Vertices) != 2 {
+		t.Errorf("Faces with less than three vertices must be kept")
+	}
+}
+
+func TestModelTriangulate(t *testing.T) {
+	p := NewLiteralParser(`v 0.0 0.0 0.0
+v 1.0 0.0 0.0
+v 1.0 1.0 0.0
+v 0.0 1.0 0.0
+o First
+f 1 2 3
+o Second
+g quads
+f 1 2 3 4
+f 4 3 2 1
+`)
+	go p.Parse()
+
+	m, err := LoadModel(p.Tokens)
+	if err != nil {
+		t.Fatalf("Unable to load model: %v", err)
+	}
+	m.Triangulate()
+	if r := m.Object("First").Ranges; len(r) != 1 || r[0] != (FaceRange{0, 1}) {
+		t.Errorf("Invalid ranges for First %v", r)
+	}
+	if r := m.Object("Second").Group("quads").Ranges; len(r) != 1 || r[0] != (FaceRange{1, 5}) {
+		t.Errorf("Invalid ranges for quads %v", r)
+	}
+}
@@ -0,0 +1,430 @@
+package wfobj
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Options used by WriteMesh
+type WriteOptions struct {
+	// Digits after the decimal point, zero or less writes
+	// the shortest representation that reads back the same value
+	Precision int
+	// Lines written as comments at the top of the file
+	Comments []string
+	// Write the mtllib and usemtl statements
+	Materials bool
+	// Files of the mtllib statement, Mesh.MaterialLibs is used if empty
+	MaterialLibs []string
+}
+
+// Deduplicated list of vertices
+type vertexPool struct {
+	list    VertexList
+	indices map[Vertex]int
+}
+
+// Return the one based index of v, adding it to the pool if needed
+func (p *vertexPool) index(v Vertex) int {
+	if p.indices == nil {
+		p.indices = make(map[Vertex]int)
+	}
+	idx, ok := p.indices[v]
+	if !ok {
+		p.list = append(p.list, v)
+		idx = len(p.list)
+		p.indices[v] = idx
+	}
+	return idx
+}
+
+// The information of a v statement
+type position struct {
+	v Vertex
+	w float32
+	c Color
+	// false if c must not be written
+	colored bool
+}
+
+// Deduplicated list of positions
+type positionPool struct {
+	list    []position
+	indices map[position]int
+}
+
+// Return the one based index of p, adding it to the pool if needed
+func (p *positionPool) index(pos position) int {
+	if p.indices == nil {
+		p.indices = make(map[position]int)
+	}
+	idx, ok := p.indices[pos]
+	if !ok {
+		p.list = append(p.list, pos)
+		idx = len(p.list)
+		p.indices[pos] = idx
+	}
+	return idx
+}
+
+type meshWriter struct {
+	w         *bufio.Writer
+	opts      WriteOptions
+	vertices  positionPool
+	texcoords vertexPool
+	normals   vertexPool
+	// one based indices of each face
+	faces [][]Index
+	// one based indices of the points and of each line
+	points []int
+	lines  [][]Index
+}
+
+// Format a number using the configured precision
+func (m *meshWriter) number(f float32) string {
+	if m.opts.Precision <= 0 {
+		return formatFloat(f)
+	}
+	return strconv.FormatFloat(float64(f), 'f', m.opts.Precision, 32)
+}
+
+// Return the one based index of the i-th vertex of a
+// face, line or the points in the vertices pool
+//
+// Weights and colors are only used if there is one for each vertex
+func (m *meshWriter) vertexIndex(vertices VertexList, weights []float32, colors []Color, i int) int {
+	pos := position{vertices[i], 1, Color{}, false}
+	if len(weights) == len(vertices) {
+		pos.w = weights[i]
+	}
+	if len(colors) == len(vertices) {
+		pos.c, pos.colored = colors[i], true
+	}
+	return m.vertices.index(pos)
+}
+
+// Add the vertices, texture coordinates and normals of the faces to the pools
+//
+// Texture coordinates and normals are only used if the
+// face has one for each vertex
+func (m *meshWriter) buildPools(mesh *Mesh) {
+	m.faces = make([][]Index, len(mesh.Faces))
+	for i, _ := range mesh.Faces {
+		f := &mesh.Faces[i]
+		indices := make([]Index, len(f.Vertices))
+		for j, _ := range f.Vertices {
+			indices[j] = Index{m.vertexIndex(f.Vertices, f.Weights, f.Colors, j), -1, -1}
+			if len(f.TexCoords) == len(f.Vertices) {
+				indices[j].TexCoord = m.texcoords.index(f.TexCoords[j])
+			}
+			if len(f.Normals) == len(f.Vertices) {
+				indices[j].Normal = m.normals.index(f.Normals[j])
+			}
+		}
+		m.faces[i] = indices
+	}
+
+	m.points = make([]int, len(mesh.Points))
+	for i, _ := range mesh.Points {
+		m.points[i] = m.vertexIndex(mesh.Points, mesh.PointWeights, mesh.PointColors, i)
+	}
+	m.lines = make([][]Index, len(mesh.Lines))
+	for i, _ := range mesh.Lines {
+		l := &mesh.Lines[i]
+		indices := make([]Index, len(l.Vertices))
+		for j, _ := range l.Vertices {
+			indices[j] = Index{m.vertexIndex(l.Vertices, l.Weights, l.Colors, j), -1, -1}
+			if len(l.TexCoords) == len(l.Vertices) {
+				indices[j].TexCoord = m.texcoords.index(l.TexCoords[j])
+			}
+		}
+		m.lines[i] = indices
+	}
+}
+
+// Write a statement with the x y z information of each vertex
+func (m *meshWriter) writeVertices(keyword string, list VertexList) {
+	for _, v := range list {
+		fmt.Fprintf(m.w, "%v %v %v %v\n", keyword, m.number(v.X), m.number(v.Y), m.number(v.Z))
+	}
+}
+
+// Write the v statements, w is omitted when one
+func (m *meshWriter) writePositions() {
+	for _, p := range m.vertices.list {
+		fmt.Fprintf(m.w, "v %v %v %v", m.number(p.v.X), m.number(p.v.Y), m.number(p.v.Z))
+		if p.w != 1 {
+			fmt.Fprintf(m.w, " %v", m.number(p.w))
+		}
+		if p.colored {
+			fmt.Fprintf(m.w, " %v %v %v", m.number(p.c.R), m.number(p.c.G), m.number(p.c.B))
+		}
+		m.w.WriteString("\n")
+	}
+}
+
+// Write the vt statements, w is omitted when zero
+func (m *meshWriter) writeTexCoords() {
+	for _, v := range m.texcoords.list {
+		if v.Z == 0 {
+			fmt.Fprintf(m.w, "vt %v %v\n", m.number(v.X), m.number(v.Y))
+		} else {
+			fmt.Fprintf(m.w, "vt %v %v %v\n", m.number(v.X), m.number(v.Y), m.number(v.Z))
+		}
+	}
+}
+
+// Write a f or l statement using the v, v/vt, v//vn or v/vt/vn form
+func (m *meshWriter) writeElement(keyword string, indices []Index) {
+	m.w.WriteString(keyword)
+	for _, idx := range indices {
+		fmt.Fprintf(m.w, " %v", idx.Vertex)
+		switch {
+		case idx.TexCoord >= 0 && idx.Normal >= 0:
+			fmt.Fprintf(m.w, "/%v/%v", idx.TexCoord, idx.Normal)
+		case idx.TexCoord >= 0:
+			fmt.Fprintf(m.w, "/%v", idx.TexCoord)
+		case idx.Normal >= 0:
+			fmt.Fprintf(m.w, "//%v", idx.Normal)
+		}
+	}
+	m.w.WriteString("\n")
+}
+
+// Check if an element with the material is written in the pass
+//
+// There is no statement to go back to no material, so the elements
+// without one are written in the first pass, before any usemtl
+func (m *meshWriter) inPass(mat *Material, pass int) bool {
+	if !m.opts.Materials || mat == nil {
+		return pass == 0
+	}
+	return pass == 1
+}
+
+func (m *meshWriter) Write(mesh *Mesh) error {
+	for _, c := range m.opts.Comments {
+		fmt.Fprintf(m.w, "# %v\n", c)
+	}
+
+	if m.opts.Materials {
+		libs := m.opts.MaterialLibs
+		if len(libs) == 0 {
+			libs = mesh.MaterialLibs
+		}
+		if len(libs) > 0 {
+			fmt.Fprintf(m.w, "mtllib %v\n", strings.Join(libs, " "))
+		}
+	}
+
+	m.buildPools(mesh)
+	m.writePositions()
+	m.writeTexCoords()
+	m.writeVertices("vn", m.normals.list)
+
+	var material *Material
+	smooth := 0
+	for pass := 0; pass < 2; pass++ {
+		for i, _ := range mesh.Faces {
+			f := &mesh.Faces[i]
+			if !m.inPass(f.Material, pass) {
+				continue
+			}
+			if pass > 0 && f.Material != material {
+				fmt.Fprintf(m.w, "usemtl %v\n", f.Material.Name)
+			}
+			material = f.Material
+			if f.SmoothingGroup != smooth {
+				if f.SmoothingGroup == 0 {
+					m.w.WriteString("s off\n")
+				} else {
+					fmt.Fprintf(m.w, "s %v\n", f.SmoothingGroup)
+				}
+				smooth = f.SmoothingGroup
+			}
+			m.writeElement("f", m.faces[i])
+		}
+
+		if pass == 0 && len(m.points) > 0 {
+			m.w.WriteString("p")
+			for _, idx := range m.points {
+				fmt.Fprintf(m.w, " %v", idx)
+			}
+			m.w.WriteString("\n")
+		}
+		for i, _ := range mesh.Lines {
+			l := &mesh.Lines[i]
+			if !m.inPass(l.Material, pass) {
+				continue
+			}
+			if pass > 0 && l.Material != material {
+				fmt.Fprintf(m.w, "usemtl %v\n", l.Material.Name)
+			}
+			material = l.Material
+			m.writeElement("l", m.lines[i])
+		}
+	}
+	return m.w.Flush()
+}
+
+// Write the mesh as a Wavefront .obj file
+//
+// Equal vertices, texture coordinates and normals are written only once.
+// When materials are written, the faces and lines without a material
+// are moved before the ones with a material.
+// The free-form geometry (Mesh.FreeForm) isn't written
+func WriteMesh(w io.Writer, m *Mesh, opts WriteOptions) error {
+	mw := &meshWriter{w: bufio.NewWriter(w), opts: opts}
+	return mw.Write(m)
+}
+
+// Write the mesh to the given .obj file
+func WriteMeshToFile(file string, m *Mesh, opts WriteOptions) (err error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return
+	}
+	err = WriteMesh(f, m, opts)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return
+}
+
+// Format a number using the shortest representation
+func formatFloat(f float32) string {
+	return strconv.FormatFloat(float64(f), 'f', -1, 32)
+}
+
+// Format the u [v [w]] arguments of a texture option
+func formatOptionVertex(v Vertex) string {
+	return fmt.Sprintf("%v %v %v", formatFloat(v.X), formatFloat(v.Y), formatFloat(v.Z))
+}
+
+// Format a on/off texture option
+func formatFlag(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+type materialWriter struct {
+	w *bufio.Writer
+}
+
+// Write a color statement
+func (m *materialWriter) writeColor(keyword string, c Color) {
+	fmt.Fprintf(m.w, "%v %v %v %v\n", keyword, formatFloat(c.R), formatFloat(c.G), formatFloat(c.B))
+}
+
+// Write a texture map statement, only options that differ
+// from the defaults are written
+func (m *materialWriter) writeTextureMap(keyword string, t *TextureMap) {
+	if t == nil {
+		return
+	}
+	def := NewTextureMap(t.File)
+	m.w.WriteString(keyword)
+	if t.BlendU != def.BlendU {
+		fmt.Fprintf(m.w, " -blendu %v", formatFlag(t.BlendU))
+	}
+	if t.BlendV != def.BlendV {
+		fmt.Fprintf(m.w, " -blendv %v", formatFlag(t.BlendV))
+	}
+	if t.ColorCorrection != def.ColorCorrection {
+		fmt.Fprintf(m.w, " -cc %v", formatFlag(t.ColorCorrection))
+	}
+	if t.Clamp != def.Clamp {
+		fmt.Fprintf(m.w, " -clamp %v", formatFlag(t.Clamp))
+	}
+	if t.BumpMultiplier != def.BumpMultiplier {
+		fmt.Fprintf(m.w, " -bm %v", formatFloat(t.BumpMultiplier))
+	}
+	if t.Boost != def.Boost {
+		fmt.Fprintf(m.w, " -boost %v", formatFloat(t.Boost))
+	}
+	if t.Base != def.Base || t.Gain != def.Gain {
+		fmt.Fprintf(m.w, " -mm %v %v", formatFloat(t.Base), formatFloat(t.Gain))
+	}
+	if t.Origin != def.Origin {
+		fmt.Fprintf(m.w, " -o %v", formatOptionVertex(t.Origin))
+	}
+	if t.Scale != def.Scale {
+		fmt.Fprintf(m.w, " -s %v", formatOptionVertex(t.Scale))
+	}
+	if t.Turbulence != def.Turbulence {
+		fmt.Fprintf(m.w, " -t %v", formatOptionVertex(t.Turbulence))
+	}
+	if t.Resolution != def.Resolution {
+		fmt.Fprintf(m.w, " -texres %v", t.Resolution)
+	}
+	if len(t.Channel) > 0 {
+		fmt.Fprintf(m.w, " -imfchan %v", t.Channel)
+	}
+	if len(t.Type) > 0 {
+		fmt.Fprintf(m.w, " -type %v", t.Type)
+	}
+	fmt.Fprintf(m.w, " %v\n", t.File)
+}
+
+// Write a newmtl block
+func (m *materialWriter) writeMaterial(mat *Material) {
+	def := NewMaterial(mat.Name)
+	fmt.Fprintf(m.w, "newmtl %v\n", mat.Name)
+	m.writeColor("Ka", mat.Ambient)
+	m.writeColor("Kd", mat.Diffuse)
+	m.writeColor("Ks", mat.Specular)
+	if mat.Emissive != def.Emissive {
+		m.writeColor("Ke", mat.Emissive)
+	}
+	if mat.TransmissionFilter != def.TransmissionFilter {
+		m.writeColor("Tf", mat.TransmissionFilter)
+	}
+	fmt.Fprintf(m.w, "Ns %v\n", formatFloat(mat.SpecularExponent))
+	fmt.Fprintf(m.w, "Ni %v\n", formatFloat(mat.OpticalDensity))
+	fmt.Fprintf(m.w, "d %v\n", formatFloat(mat.Dissolve))
+	if mat.Sharpness != def.Sharpness {
+		fmt.Fprintf(m.w, "sharpness %v\n", formatFloat(mat.Sharpness))
+	}
+	fmt.Fprintf(m.w, "illum %v\n", mat.Illum)
+	m.writeTextureMap("map_Ka", mat.AmbientMap)
+	m.writeTextureMap("map_Kd", mat.DiffuseMap)
+	m.writeTextureMap("map_Ks", mat.SpecularMap)
+	m.writeTextureMap("map_Ke", mat.EmissiveMap)
+	m.writeTextureMap("map_Ns", mat.SpecularExponentMap)
+	m.writeTextureMap("map_d", mat.DissolveMap)
+	m.writeTextureMap("map_Bump", mat.BumpMap)
+	m.writeTextureMap("disp", mat.DisplacementMap)
+	m.writeTextureMap("decal", mat.DecalMap)
+	m.writeTextureMap("refl", mat.ReflectionMap)
+}
+
+// Write the materials as a Wavefront .mtl file
+func WriteMaterials(w io.Writer, mats []Material) error {
+	mw := &materialWriter{bufio.NewWriter(w)}
+	for i, _ := range mats {
+		if i > 0 {
+			mw.w.WriteString("\n")
+		}
+		mw.writeMaterial(&mats[i])
+	}
+	return mw.w.Flush()
+}
+
+// Write the materials to the given .mtl file
+func WriteMaterialsToFile(file string, mats []Material) (err error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return
+	}
+	err = WriteMaterials(f, mats)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return
+}
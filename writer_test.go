@@ -0,0 +1,232 @@
+package wfobj
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteMesh(t *testing.T) {
+	for _, test := range testdata {
+		if test.ignore {
+			continue
+		}
+		buf := &bytes.Buffer{}
+		err := WriteMesh(buf, test.mesh, WriteOptions{Materials: true})
+		if err != nil {
+			t.Fatalf("%v: unable to write mesh: %v", test.title, err)
+		}
+
+		m, err := LoadMeshFromReader(buf)
+		if err != nil {
+			t.Fatalf("%v: unable to read the mesh back: %v", test.title, err)
+		}
+		if len(m.Faces) != len(test.mesh.Faces) {
+			t.Fatalf("%v: expecting %v faces but got %v", test.title, len(test.mesh.Faces), len(m.Faces))
+		}
+		for i, _ := range m.Faces {
+			expected := &test.mesh.Faces[i]
+			got := &m.Faces[i]
+			if !got.Same(expected) || !got.Normals.Same(expected.Normals) || !got.TexCoords.Same(expected.TexCoords) {
+				t.Errorf("%v: faces are different. Expecting %v got %v", test.title, expected, got)
+			}
+			if materialName(got.Material) != materialName(expected.Material) {
+				t.Errorf("%v: expecting material %q got %q", test.title, materialName(expected.Material), materialName(got.Material))
+			}
+			if got.SmoothingGroup != expected.SmoothingGroup {
+				t.Errorf("%v: expecting smoothing group %v got %v", test.title, expected.SmoothingGroup, got.SmoothingGroup)
+			}
+		}
+	}
+}
+
+func TestWriteMeshOptions(t *testing.T) {
+	mesh := &Mesh{
+		Faces: []Face{
+			Face{Vertices: VertexList{Vertex{0, 0, 0}, Vertex{1, 0, 0}, Vertex{0.5, 1, 0}}},
+			Face{Vertices: VertexList{Vertex{1, 0, 0}, Vertex{0, 0, 0}, Vertex{0.5, -1, 0}}},
+		},
+	}
+	buf := &bytes.Buffer{}
+	err := WriteMesh(buf, mesh, WriteOptions{
+		Precision:    2,
+		Comments:     []string{"generated"},
+		Materials:    true,
+		MaterialLibs: []string{"a.mtl"},
+	})
+	if err != nil {
+		t.Fatalf("Unable to write mesh: %v", err)
+	}
+	expected := `# generated
+mtllib a.mtl
+v 0.00 0.00 0.00
+v 1.00 0.00 0.00
+v 0.50 1.00 0.00
+v 0.50 -1.00 0.00
+f 1 2 3
+f 2 1 4
+`
+	if buf.String() != expected {
+		t.Errorf("Expecting:\n%v\ngot:\n%v", expected, buf.String())
+	}
+}
+
+func TestWriteMeshWithoutMaterial(t *testing.T) {
+	red := &Material{Name: "red"}
+	mesh := &Mesh{
+		Faces: []Face{
+			Face{Vertices: VertexList{Vertex{0, 0, 0}, Vertex{1, 0, 0}, Vertex{0, 1, 0}}, Material: red},
+			Face{Vertices: VertexList{Vertex{1, 0, 0}, Vertex{0, 0, 0}, Vertex{0, -1, 0}}},
+		},
+		Lines: []Line{
+			Line{Vertices: VertexList{Vertex{0, 0, 0}, Vertex{0, 1, 0}}, Material: red},
+			Line{Vertices: VertexList{Vertex{0, 0, 0}, Vertex{1, 0, 0}}},
+		},
+	}
+	buf := &bytes.Buffer{}
+	if err := WriteMesh(buf, mesh, WriteOptions{Materials: true}); err != nil {
+		t.Fatalf("Unable to write mesh: %v", err)
+	}
+	expected := `v 0 0 0
+v 1 0 0
+v 0 1 0
+v 0 -1 0
+f 2 1 4
+l 1 2
+usemtl red
+f 1 2 3
+l 1 3
+`
+	if buf.String() != expected {
+		t.Errorf("Expecting:\n%v\ngot:\n%v", expected, buf.String())
+	}
+
+	// without materials the order is kept
+	buf.Reset()
+	if err := WriteMesh(buf, mesh, WriteOptions{}); err != nil {
+		t.Fatalf("Unable to write mesh: %v", err)
+	}
+	if !strings.HasSuffix(buf.String(), "f 1 2 3\nf 2 1 4\nl 1 3\nl 1 2\n") {
+		t.Errorf("Invalid order of the elements:\n%v", buf.String())
+	}
+}
+
+func TestWriteMeshVertexInfo(t *testing.T) {
+	mesh := &Mesh{
+		Faces: []Face{
+			Face{
+				Vertices: VertexList{Vertex{0, 0, 0}, Vertex{1, 0, 0}, Vertex{0, 1, 0}},
+				Weights:  []float32{1, 2, 1},
+				Colors:   []Color{Color{1, 0, 0}, Color{0, 1, 0}, Color{0, 0, 1}},
+			},
+		},
+	}
+	buf := &bytes.Buffer{}
+	if err := WriteMesh(buf, mesh, WriteOptions{}); err != nil {
+		t.Fatalf("Unable to write mesh: %v", err)
+	}
+	expected := `v 0 0 0 1 0 0
+v 1 0 0 2 0 1 0
+v 0 1 0 0 0 1
+f 1 2 3
+`
+	if buf.String() != expected {
+		t.Errorf("Expecting:\n%v\ngot:\n%v", expected, buf.String())
+	}
+}
+
+func TestWriteMeshPointsAndLines(t *testing.T) {
+	mesh := &Mesh{
+		Points: VertexList{Vertex{0, 0, 0}, Vertex{2, 0, 0}},
+		Lines: []Line{
+			Line{Vertices: VertexList{Vertex{0, 0, 0}, Vertex{1, 0, 0}}},
+			Line{Vertices: VertexList{Vertex{1, 0, 0}, Vertex{2, 0, 0}}, TexCoords: VertexList{Vertex{0, 0, 0}, Vertex{1, 0, 0}}},
+		},
+	}
+	buf := &bytes.Buffer{}
+	if err := WriteMesh(buf, mesh, WriteOptions{}); err != nil {
+		t.Fatalf("Unable to write mesh: %v", err)
+	}
+	expected := `v 0 0 0
+v 2 0 0
+v 1 0 0
+vt 0 0
+vt 1 0
+p 1 2
+l 1 3
+l 3/1 2/2
+`
+	if buf.String() != expected {
+		t.Errorf("Expecting:\n%v\ngot:\n%v", expected, buf.String())
+	}
+}
+
+func TestWritePointsAndLinesVertexInfo(t *testing.T) {
+	lit := "v 0 0 0 2 1 0 0\nv 1 0 0 0 1 0\nv 0 1 0\nf 1 2 3\np 1 3\nl 1 2\n"
+	m, err := LoadMeshFromReader(strings.NewReader(lit))
+	if err != nil {
+		t.Fatalf("Unable to load mesh: %v", err)
+	}
+	if !reflect.DeepEqual(m.PointWeights, []float32{2, 1}) || !reflect.DeepEqual(m.PointColors, []Color{{1, 0, 0}, {1, 1, 1}}) {
+		t.Errorf("Invalid point information %v %v", m.PointWeights, m.PointColors)
+	}
+	l := m.Lines[0]
+	if !reflect.DeepEqual(l.Weights, []float32{2, 1}) || !reflect.DeepEqual(l.Colors, []Color{{1, 0, 0}, {0, 1, 0}}) {
+		t.Errorf("Invalid line information %v %v", l.Weights, l.Colors)
+	}
+
+	buf := &bytes.Buffer{}
+	if err = WriteMesh(buf, m, WriteOptions{}); err != nil {
+		t.Fatalf("Unable to write mesh: %v", err)
+	}
+	expected := `v 0 0 0 2 1 0 0
+v 1 0 0 0 1 0
+v 0 1 0 1 1 1
+f 1 2 3
+p 1 3
+l 1 2
+`
+	if buf.String() != expected {
+		t.Errorf("Expecting:\n%v\ngot:\n%v", expected, buf.String())
+	}
+}
+
+func TestWriteMaterials(t *testing.T) {
+	mats, err := LoadMaterials(strings.NewReader(mtllit))
+	if err != nil {
+		t.Fatalf("Unable to load materials: %v", err)
+	}
+	mats[0].DiffuseMap.BlendU = false
+	mats[0].DiffuseMap.Channel = "r"
+
+	buf := &bytes.Buffer{}
+	if err = WriteMaterials(buf, mats); err != nil {
+		t.Fatalf("Unable to write materials: %v", err)
+	}
+	if !strings.Contains(buf.String(), "map_Kd -blendu off -clamp on -o 0.5 0 0 -s 2 2 1 -imfchan r brick.png\n") {
+		t.Errorf("Invalid texture map statement in:\n%v", buf.String())
+	}
+
+	back, err := LoadMaterials(buf)
+	if err != nil {
+		t.Fatalf("Unable to read the materials back: %v", err)
+	}
+	if len(back) != len(mats) {
+		t.Fatalf("Expecting %v materials but got %v", len(mats), len(back))
+	}
+	for i, _ := range mats {
+		if back[i].Name != mats[i].Name || back[i].Diffuse != mats[i].Diffuse || back[i].Dissolve != mats[i].Dissolve {
+			t.Errorf("Materials are different. Expecting %v got %v", mats[i], back[i])
+		}
+		if (mats[i].DiffuseMap == nil) != (back[i].DiffuseMap == nil) {
+			t.Fatalf("Expecting diffuse map %v got %v", mats[i].DiffuseMap, back[i].DiffuseMap)
+		}
+		if mats[i].DiffuseMap != nil && *back[i].DiffuseMap != *mats[i].DiffuseMap {
+			t.Errorf("Texture maps are different. Expecting %v got %v", mats[i].DiffuseMap, back[i].DiffuseMap)
+		}
+		if mats[i].BumpMap != nil && *back[i].BumpMap != *mats[i].BumpMap {
+			t.Errorf("Texture maps are different. Expecting %v got %v", mats[i].BumpMap, back[i].BumpMap)
+		}
+	}
+}